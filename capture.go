@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bxffour/nstats/internal/stats"
+	"github.com/urfave/cli/v2"
+)
+
+var captureCommand = cli.Command{
+	Name:  "capture",
+	Usage: "write the samples to a file as newline delimited JSON, printing progress to stderr, until interrupted",
+	Flags: []cli.Flag{
+		&pinPathFlag,
+		&cli.StringFlag{
+			Name:     "out",
+			Required: true,
+			Usage:    "file to append the samples to, gzip compressed if it ends in .gz",
+		},
+		&cli.DurationFlag{
+			Name:  "interval",
+			Value: stats.DefaultInterval,
+			Usage: "time between two samples",
+		},
+		&cli.DurationFlag{
+			Name:  "progress",
+			Value: 5 * time.Second,
+			Usage: "time between two progress lines",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Duration("interval") < time.Millisecond {
+			return fmt.Errorf("invalid interval %s: the minimum is 1ms", ctx.Duration("interval"))
+		}
+
+		if ctx.Duration("progress") <= 0 {
+			return fmt.Errorf("invalid progress interval %s", ctx.Duration("progress"))
+		}
+
+		maps, err := openStatsMaps("", nil, true)
+		if err != nil {
+			return err
+		}
+
+		m := maps[0]
+		defer m.Close()
+
+		out := ctx.String("out")
+
+		file, err := stats.NewSink("json:" + out)
+		if err != nil {
+			return err
+		}
+
+		counter := &countingSink{}
+		opts := stats.Options{
+			Interval: ctx.Duration("interval"),
+			Sinks:    []stats.OutputSink{file, counter},
+		}
+
+		sigCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		start := time.Now()
+		done := make(chan struct{})
+
+		go func() {
+			ticker := time.NewTicker(ctx.Duration("progress"))
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					fmt.Fprintf(os.Stderr, "captured %d samples, %s\n", counter.n.Load(), time.Since(start).Round(time.Second))
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		err = stats.StreamMaps(sigCtx, []stats.Source{{Map: m}}, opts)
+		close(done)
+
+		// closing flushes what is buffered, gzip included
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "captured %d samples over %s to %s\n", counter.n.Load(), time.Since(start).Round(time.Second), out)
+
+		return nil
+	},
+}
+
+// countingSink counts the samples written, for the progress of capture
+type countingSink struct {
+	n atomic.Int64
+}
+
+func (c *countingSink) Write(stats.Sample) error {
+	c.n.Add(1)
+	return nil
+}
+
+func (c *countingSink) Close() error { return nil }
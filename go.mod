@@ -6,6 +6,7 @@ require (
 	github.com/cilium/ebpf v0.11.0
 	github.com/gizak/termui/v3 v3.1.0
 	github.com/urfave/cli/v2 v2.25.7
+	golang.org/x/sys v0.6.0
 )
 
 require (
@@ -16,5 +17,4 @@ require (
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
 	golang.org/x/exp v0.0.0-20230224173230-c95f2b4c22f2 // indirect
-	golang.org/x/sys v0.6.0 // indirect
 )
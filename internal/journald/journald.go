@@ -0,0 +1,110 @@
+// Package journald sends entries to the systemd journal over its native
+// protocol, without linking against libsystemd.
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Socket is where journald listens for native protocol entries.
+const Socket = "/run/systemd/journal/socket"
+
+// Priorities of entries, as in syslog(3).
+const (
+	PriErr     = 3
+	PriWarning = 4
+	PriNotice  = 5
+	PriInfo    = 6
+)
+
+// Conn is a connection to the journal.
+type Conn struct {
+	conn *net.UnixConn
+}
+
+// Dial connects to the journal. It fails when journald isn't running, e.g.
+// on hosts without systemd.
+func Dial() (*Conn, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: Socket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{conn: conn}, nil
+}
+
+// Send writes an entry with the message, priority and extra fields. Field
+// names must be upper case letters, digits and underscores.
+func (c *Conn) Send(priority int, message string, fields map[string]string) error {
+	var buf bytes.Buffer
+
+	writeField(&buf, "PRIORITY", strconv.Itoa(priority))
+	writeField(&buf, "MESSAGE", message)
+
+	// sorted so entries are laid out the same way every time
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		writeField(&buf, name, fields[name])
+	}
+
+	_, err := c.conn.Write(buf.Bytes())
+
+	return err
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// writeField appends a field, using the length-prefixed form for values the
+// plain NAME=value form can't hold
+func writeField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// Writer returns an io.Writer sending every write as an entry, for use with
+// log.SetOutput. Writes mentioning "WARNING" get PriWarning, the others
+// priority.
+func (c *Conn) Writer(priority int) *Writer {
+	return &Writer{conn: c, priority: priority}
+}
+
+// Writer sends every write to the journal as one entry.
+type Writer struct {
+	conn     *Conn
+	priority int
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+
+	priority := w.priority
+	if strings.Contains(msg, "WARNING") {
+		priority = PriWarning
+	}
+
+	if err := w.conn.Send(priority, msg, nil); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
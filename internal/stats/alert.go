@@ -0,0 +1,181 @@
+package stats
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var alertRe = regexp.MustCompile(`^\s*([^:\s]+)\s*:\s*([A-Za-z_]+)\s*(>=|<=|==|!=|>|<)\s*(\S+)\s*$`)
+
+// Alert is a condition on a field of an action, e.g. the drop rate going
+// over 1000 pps, reported when it starts to hold and once again when it
+// stops, rather than on every tick it holds.
+type Alert struct {
+	Spec   string
+	Action uint
+
+	cond condition
+
+	// firing is set while the condition holds
+	firing bool
+
+	// notified is the state last reported, at notifiedAt, which may lag
+	// behind firing with Options.NotifyMinInterval
+	notified   bool
+	notifiedAt time.Time
+}
+
+// ParseAlert parses an alert of the form action:field op number, e.g.
+// "XDP_ABORT:pps>100". Actions are given as in ParseGroup and fields as in
+// ParseFilter.
+func ParseAlert(spec string, names ActionNames) (*Alert, error) {
+	m := alertRe.FindStringSubmatch(spec)
+	if m == nil {
+		return nil, fmt.Errorf("invalid alert %q: expected action:field op number, e.g. drop:pps>1000", spec)
+	}
+
+	act, err := names.lookup(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert %q: %w", spec, err)
+	}
+
+	field := strings.ToLower(m[2])
+	if _, ok := sampleFields[field]; !ok {
+		return nil, fmt.Errorf("invalid alert %q: unknown field %q (known fields: %s)", spec, m[2], strings.Join(filterFields(), ", "))
+	}
+
+	v, err := strconv.ParseFloat(m[4], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert %q: %q is not a number", spec, m[4])
+	}
+
+	return &Alert{Spec: strings.TrimSpace(spec), Action: act, cond: condition{field: field, op: m[3], value: v}}, nil
+}
+
+// AlertEvent is an alert starting or stopping to hold, as written to the
+// structured outputs.
+type AlertEvent struct {
+	Alert     string    `json:"alert"`
+	State     string    `json:"state"`
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"`
+	Field     string    `json:"field"`
+	Value     float64   `json:"value"`
+	Op        string    `json:"op"`
+	Threshold float64   `json:"threshold"`
+}
+
+// The states of an AlertEvent
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// String describes the event, e.g.
+// "ALERT firing: XDP_DROP pps is 1,520 (drop:pps>1000)".
+func (e AlertEvent) String() string {
+	return fmt.Sprintf("ALERT %s: %s %s is %s (%s)", e.State, e.Action, e.Field, formatAuto(e.Value), e.Alert)
+}
+
+// checkAlerts returns the alerts starting or stopping to hold with the tick,
+// or, with NotifyMinInterval, those whose state differs from the one last
+// reported once the interval is over. An alert holds if it holds for the
+// sample of its action from any source, the first such sample being reported.
+func (o *Options) checkAlerts(samples []Sample) []AlertEvent {
+	var events []AlertEvent
+
+	for _, a := range o.Alerts {
+		var (
+			name    = o.ActionNames.name(a.Action)
+			seen    bool
+			holding bool
+			at      Sample
+		)
+
+		for _, s := range samples {
+			if s.Action != name {
+				continue
+			}
+
+			match := a.cond.match(sampleFields[a.cond.field](s))
+			if !seen || (match && !holding) {
+				at = s
+			}
+
+			seen = true
+			holding = holding || match
+		}
+
+		if !seen {
+			continue
+		}
+
+		a.firing = holding
+
+		if holding == a.notified || !o.notifyDue(a, at.Timestamp) {
+			continue
+		}
+
+		a.notified, a.notifiedAt = holding, at.Timestamp
+
+		e := AlertEvent{
+			Alert:     a.Spec,
+			State:     AlertResolved,
+			Timestamp: at.Timestamp,
+			Action:    name,
+			Field:     a.cond.field,
+			Value:     sampleFields[a.cond.field](at),
+			Op:        a.cond.op,
+			Threshold: a.cond.value,
+		}
+
+		if holding {
+			e.State = AlertFiring
+		}
+
+		events = append(events, e)
+	}
+
+	return events
+}
+
+// notifyDue reports whether a report of a may be made at now, NotifyMinInterval
+// having passed since the last one
+func (o *Options) notifyDue(a *Alert, now time.Time) bool {
+	return o.NotifyMinInterval <= 0 || a.notifiedAt.IsZero() || now.Sub(a.notifiedAt) >= o.NotifyMinInterval
+}
+
+// raiseAlerts reports the alerts starting or stopping to hold with the tick
+// on stderr, or once the table is closed, and to the sinks recording
+// annotations
+func (o *Options) raiseAlerts(samples []Sample) error {
+	for _, e := range o.checkAlerts(samples) {
+		if o.tui {
+			o.alerts = append(o.alerts, e)
+		} else {
+			log.Println(e)
+		}
+
+		e := e
+		if err := o.writeAnnotation(Annotation{Timestamp: e.Timestamp, Text: e.String(), Alert: &e}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alerting reports whether an alert on the action of s is firing
+func (o *Options) alerting(s Sample) bool {
+	for _, a := range o.Alerts {
+		if a.firing && o.ActionNames.name(a.Action) == s.Action {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAlertFieldCase(t *testing.T) {
+	a, err := ParseAlert("drop:PPS>1000", ActionNames{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.cond.field != "pps" {
+		t.Errorf("field %q, want pps", a.cond.field)
+	}
+}
+
+func TestNotifyMinInterval(t *testing.T) {
+	a, err := ParseAlert("drop:pps>100", ActionNames{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := &Options{Alerts: []*Alert{a}, NotifyMinInterval: time.Minute}
+	start := time.Unix(1700000000, 0)
+
+	// the drop rate at each second, flapping around the threshold
+	var states []string
+	for i, pps := range []float64{200, 0, 200, 0, 200, 0, 0} {
+		at := start.Add(time.Duration(i) * time.Second)
+		if i == 6 {
+			at = start.Add(time.Minute)
+		}
+
+		for _, e := range o.checkAlerts([]Sample{{Action: "XDP_DROP", PPS: pps, Timestamp: at}}) {
+			states = append(states, e.State)
+		}
+
+		if a.firing != (pps > 100) {
+			t.Errorf("tick %d: firing = %v with %v pps", i, a.firing, pps)
+		}
+	}
+
+	if len(states) != 2 || states[0] != AlertFiring || states[1] != AlertResolved {
+		t.Errorf("notified %v, want [firing resolved]", states)
+	}
+}
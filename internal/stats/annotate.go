@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Annotation is an external marker, such as a test phase, interleaved with
+// the samples of the structured outputs.
+type Annotation struct {
+	Timestamp time.Time
+	Text      string
+
+	// Alert is the alert event the annotation reports, if any
+	Alert *AlertEvent
+}
+
+// annotator is implemented by sinks that can record annotations
+type annotator interface {
+	Annotate(Annotation) error
+}
+
+// ReadAnnotations sends every non-empty line read from r as an annotation,
+// stamped when it was read, and closes the channel at the end of r.
+func ReadAnnotations(r io.Reader) <-chan Annotation {
+	ch := make(chan Annotation)
+
+	go func() {
+		defer close(ch)
+
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			if text := strings.TrimSpace(sc.Text()); text != "" {
+				ch <- Annotation{Timestamp: time.Now(), Text: text}
+			}
+		}
+	}()
+
+	return ch
+}
+
+// writeAnnotation hands a to every sink recording annotations
+func (o *Options) writeAnnotation(a Annotation) error {
+	for _, sink := range o.Sinks {
+		if an, ok := sink.(annotator); ok {
+			if err := an.Annotate(a); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Annotate writes a as a JSON object of its own, told apart from the samples
+// by its annotation field. Alerts also have an alert object.
+func (j *jsonSink) Annotate(a Annotation) error {
+	return j.enc.Encode(struct {
+		SchemaVersion int         `json:"schema_version"`
+		Timestamp     time.Time   `json:"timestamp"`
+		Annotation    string      `json:"annotation"`
+		Alert         *AlertEvent `json:"alert,omitempty"`
+	}{SchemaVersion, a.Timestamp, a.Text, a.Alert})
+}
+
+// Annotate writes a as a row with ANNOTATION, or ALERT for alerts, as its
+// action and the text as its label, the other fields being empty.
+func (c *csvSink) Annotate(a Annotation) error {
+	record := make([]string, len(fields))
+
+	for i, f := range fields {
+		switch f.name {
+		case "schema_version":
+			record[i] = strconv.Itoa(SchemaVersion)
+		case "timestamp":
+			record[i] = a.Timestamp.Format(time.RFC3339Nano)
+		case "action":
+			record[i] = "ANNOTATION"
+			if a.Alert != nil {
+				record[i] = "ALERT"
+			}
+		case "label":
+			record[i] = a.Text
+		}
+	}
+
+	if c.header {
+		c.header = false
+
+		if err := c.w.Write(csvHeader()); err != nil {
+			return err
+		}
+	}
+
+	if err := c.w.Write(record); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+
+	return c.w.Error()
+}
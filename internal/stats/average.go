@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// averageTracker holds the first sample of every action seen this session,
+// which the session average rates are taken from
+type averageTracker struct {
+	first map[string]Sample
+}
+
+func newAverageTracker() *averageTracker {
+	return &averageTracker{first: make(map[string]Sample)}
+}
+
+// update records the samples of the actions seen for the first time. Actions
+// whose counters went backward start over from their new sample.
+func (t *averageTracker) update(samples []Sample) {
+	for _, s := range samples {
+		key := s.Map + "\x00" + s.Action
+
+		if first, ok := t.first[key]; !ok || s.Packets < first.Packets || s.Bytes < first.Bytes {
+			t.first[key] = s
+		}
+	}
+}
+
+// average returns the average rates of the action of s since it was first
+// seen, e.g. "5340 pps, 41 Mbit/s", or "-" until it is seen again
+func (t *averageTracker) average(s Sample) string {
+	if t == nil {
+		return ""
+	}
+
+	first, ok := t.first[s.Map+"\x00"+s.Action]
+	if !ok {
+		return "-"
+	}
+
+	secs := s.Timestamp.Sub(first.Timestamp).Seconds()
+	if secs <= 0 {
+		return "-"
+	}
+
+	pps := float64(s.Packets-first.Packets) / secs
+	bps := computeBitsPerSec(float64(s.Bytes-first.Bytes), secs)
+
+	return fmt.Sprintf("%.0f pps, %s", pps, strings.TrimSpace(formatRate(bps, "bit")))
+}
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"fmt"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// dropBanner is a line above the table telling how many packets were dropped
+// since the session started, shown once any are until it is dismissed
+type dropBanner struct {
+	*widgets.Paragraph
+
+	drops     uint64
+	dismissed bool
+}
+
+func newDropBanner(opts *Options) *dropBanner {
+	b := &dropBanner{Paragraph: widgets.NewParagraph()}
+
+	b.TextStyle = opts.alertStyle()
+	b.BorderStyle = ui.NewStyle(opts.color(ui.ColorRed))
+
+	return b
+}
+
+// update takes the drops since the start from the records of the sources
+// and the drop rate from the samples of the tick
+func (b *dropBanner) update(opts *Options, baseline, records []StatsRecord, samples []Sample) {
+	const drop = 1 // XDP_DROP
+
+	b.drops = 0
+	for i := range records {
+		if i < len(baseline) {
+			b.drops += sinceZero(records[i].record(drop).total.rxPackets, baseline[i].record(drop).total.rxPackets)
+		}
+	}
+
+	var pps float64
+	for _, s := range samples {
+		if s.Action == opts.ActionNames.name(drop) {
+			pps += s.PPS
+		}
+	}
+
+	b.Text = opts.alert(fmt.Sprintf("%s packets dropped since the start, %.0f pps now (d dismisses)", formatThousands(b.drops), pps))
+}
+
+func (b *dropBanner) visible() bool {
+	return b.drops > 0 && !b.dismissed
+}
+
+func (b *dropBanner) Draw(buf *ui.Buffer) {
+	if b.visible() {
+		b.Paragraph.Draw(buf)
+	}
+}
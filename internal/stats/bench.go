@@ -0,0 +1,223 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary describes the values a rate took over a run.
+type Summary struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+	P95 float64 `json:"p95"`
+}
+
+// summarize returns the summary of values, which it sorts
+func summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+
+	sort.Float64s(values)
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	// nearest rank
+	p95 := values[int(math.Ceil(0.95*float64(len(values))))-1]
+
+	return Summary{Min: values[0], Avg: sum / float64(len(values)), Max: values[len(values)-1], P95: p95}
+}
+
+// BenchAction is the throughput of one action over a benchmark run.
+type BenchAction struct {
+	Action string `json:"action"`
+	Map    string `json:"map,omitempty"`
+
+	// Packets and Bytes are counted during the run only
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+
+	PPS Summary `json:"pps"`
+	BPS Summary `json:"bps"`
+}
+
+// BenchReport is the result of Bench.
+type BenchReport struct {
+	SchemaVersion int           `json:"schema_version"`
+	Duration      float64       `json:"duration"`
+	Samples       int           `json:"samples"`
+	Actions       []BenchAction `json:"actions"`
+}
+
+// benchSink gathers the samples of a run, by action
+type benchSink struct {
+	order   []string
+	samples map[string][]Sample
+}
+
+func (b *benchSink) Write(s Sample) error {
+	key := s.Map + "\x00" + s.Action
+	if _, ok := b.samples[key]; !ok {
+		b.order = append(b.order, key)
+	}
+
+	b.samples[key] = append(b.samples[key], s)
+
+	return nil
+}
+
+func (b *benchSink) Close() error { return nil }
+
+// Bench samples the sources for duration, or until ctx is cancelled, and
+// summarises the rates every action saw. opts.Sinks are written to as well.
+func Bench(ctx context.Context, sources []Source, opts Options, duration time.Duration) (*BenchReport, error) {
+	sink := &benchSink{samples: make(map[string][]Sample)}
+	opts.Sinks = append(append([]OutputSink(nil), opts.Sinks...), sink)
+
+	runCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := StreamMaps(runCtx, sources, opts); err != nil {
+		return nil, err
+	}
+
+	return sink.report(time.Since(start)), nil
+}
+
+// report summarises the samples gathered over a run of duration
+func (b *benchSink) report(duration time.Duration) *BenchReport {
+	report := &BenchReport{SchemaVersion: SchemaVersion, Duration: duration.Seconds()}
+
+	for _, key := range b.order {
+		samples := b.samples[key]
+
+		act := BenchAction{Action: samples[0].Action, Map: samples[0].Map}
+
+		pps := make([]float64, 0, len(samples))
+		bps := make([]float64, 0, len(samples))
+
+		for _, s := range samples {
+			pps = append(pps, s.PPS)
+			bps = append(bps, s.BPS)
+
+			// the rates times the period give back the counts of the
+			// period
+			act.Packets += uint64(math.Round(s.PPS * s.Period))
+			act.Bytes += uint64(math.Round(s.BPS / 8 * s.Period))
+		}
+
+		act.PPS, act.BPS = summarize(pps), summarize(bps)
+
+		report.Samples = len(samples)
+		report.Actions = append(report.Actions, act)
+	}
+
+	return report
+}
+
+// Profile is the result of SampleProfile: every sample of a short run along with
+// their summary.
+type Profile struct {
+	SchemaVersion int     `json:"schema_version"`
+	Interval      float64 `json:"interval"`
+
+	// Ticks holds the samples of every tick, in order
+	Ticks   [][]Sample   `json:"ticks"`
+	Summary *BenchReport `json:"summary"`
+}
+
+// SampleProfile reads the sources for n intervals of opts.Interval and
+// returns the samples of every tick along with their summary. Samples not
+// matching opts.Filter are left out. A cancelled ctx ends the run early with
+// the ticks read so far.
+func SampleProfile(ctx context.Context, sources []Source, opts Options, n int) (*Profile, error) {
+	opts.start = opts.clock().Now()
+
+	reader, err := opts.newReader(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	var (
+		sink    = &benchSink{samples: make(map[string][]Sample)}
+		profile = &Profile{SchemaVersion: SchemaVersion, Interval: interval.Seconds()}
+	)
+
+	for len(profile.Ticks) < n {
+		timer := time.NewTimer(opts.tickInterval())
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			n = len(profile.Ticks)
+			continue
+		}
+
+		_, samples, err := reader.read()
+		if errors.Is(err, errUnstableRead) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		tick := make([]Sample, 0, len(samples))
+		for _, s := range samples {
+			if opts.Filter.Match(s) {
+				tick = append(tick, s)
+				sink.Write(s)
+			}
+		}
+
+		profile.Ticks = append(profile.Ticks, tick)
+	}
+
+	profile.Summary = sink.report(opts.clock().Now().Sub(opts.start))
+
+	return profile, nil
+}
+
+// WriteText writes the report as a table.
+func (r *BenchReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(tw, "Action\tPackets\tBytes\tMin pps\tAvg pps\tMax pps\tp95 pps\tMin Mbps\tAvg Mbps\tMax Mbps\tp95 Mbps\t\n")
+
+	for _, a := range r.Actions {
+		action := a.Action
+		if a.Map != "" {
+			action = a.Map + " " + action
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%.0f\t%.0f\t%.0f\t%.0f\t%.2f\t%.2f\t%.2f\t%.2f\t\n", action, a.Packets, formatBytes(a.Bytes),
+			a.PPS.Min, a.PPS.Avg, a.PPS.Max, a.PPS.P95,
+			a.BPS.Min/1e6, a.BPS.Avg/1e6, a.BPS.Max/1e6, a.BPS.P95/1e6)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%d samples over %s\n", r.Samples, time.Duration(r.Duration*float64(time.Second)).Round(time.Millisecond))
+
+	return err
+}
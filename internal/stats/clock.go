@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock tells the time the counters are read at, which the periods of the
+// rates are taken over. Tests give a clock of their own to control them.
+//
+// The periods are the differences of the times told, so a clock returning
+// times carrying a monotonic reading, as time.Now does, keeps them steady
+// when NTP or an operator steps the wall clock. The records and samples are
+// passed around by value and never rounded, keeping that reading.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default clock, reading time.Now with its monotonic
+// reading
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// wallClock reads the wall clock alone, so the periods follow its steps
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now().Round(0) }
+
+// ParseClock returns the clock named by source: "monotonic", the default
+// taking the periods from the monotonic clock, or "wall", taking them from
+// the wall clock alone so they follow its steps, as when comparing with
+// tools stamping their own counters with the wall clock.
+func ParseClock(source string) (Clock, error) {
+	switch source {
+	case "", "monotonic":
+		return systemClock{}, nil
+	case "wall":
+		return wallClock{}, nil
+	default:
+		return nil, fmt.Errorf("invalid clock %q: expected monotonic or wall", source)
+	}
+}
+
+// clock returns the clock of the session, the system clock unless Clock is
+// set
+func (o *Options) clock() Clock {
+	if o.Clock == nil {
+		return systemClock{}
+	}
+
+	return o.Clock
+}
@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// steppedHost is a host whose wall clock is stepped by step while its
+// monotonic clock runs on undisturbed. A time.Time can't be built with its
+// two readings apart, so the monotonic clock tells the elapsed time on top
+// of a reading taken at start, which is all Sub looks at, and the wall clock
+// tells it stepped and without a monotonic reading, as --clock wall does.
+type steppedHost struct {
+	start         time.Time
+	elapsed, step time.Duration
+}
+
+type hostMonotonic struct{ *steppedHost }
+
+func (h hostMonotonic) Now() time.Time { return h.start.Add(h.elapsed) }
+
+type hostWall struct{ *steppedHost }
+
+func (h hostWall) Now() time.Time { return h.start.Round(0).Add(h.elapsed + h.step) }
+
+func TestParseClock(t *testing.T) {
+	for source, monotonic := range map[string]bool{"": true, "monotonic": true, "wall": false} {
+		c, err := ParseClock(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the monotonic reading is printed as m=±<seconds>
+		if got := strings.Contains(c.Now().String(), "m="); got != monotonic {
+			t.Errorf("clock %q: monotonic reading %v, want %v", source, got, monotonic)
+		}
+	}
+
+	if _, err := ParseClock("tai"); err == nil {
+		t.Error("ParseClock(\"tai\") didn't fail")
+	}
+}
+
+func TestClockWallJump(t *testing.T) {
+	host := &steppedHost{start: time.Now()}
+
+	tests := []struct {
+		name    string
+		clock   Clock
+		periods [2]float64
+		pps     [2]float64
+	}{
+		// the periods are the time elapsed, whatever the wall clock does
+		{"monotonic", hostMonotonic{host}, [2]float64{1, 1}, [2]float64{100, 100}},
+		// stepped back, the second read comes before the first and has no
+		// rates; stepped forward again, the rates are spread over the hour
+		{"wall", hostWall{host}, [2]float64{-9, 3601}, [2]float64{0, 100.0 / 3601}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host.elapsed, host.step = 0, 0
+
+			read := func(packets uint64) StatsRecord {
+				return records(tt.clock.Now(), value(t, datarecSize, packets, packets*100))
+			}
+
+			first := read(1000)
+
+			host.elapsed, host.step = time.Second, -10*time.Second
+			second := read(1100)
+
+			host.elapsed, host.step = 2*time.Second, time.Hour-10*time.Second
+			third := read(1200)
+
+			for i, s := range []Sample{calcStats(first, second)[0], calcStats(second, third)[0]} {
+				if s.Period != tt.periods[i] {
+					t.Errorf("read %d: period %vs, want %vs", i+2, s.Period, tt.periods[i])
+				}
+
+				if s.PPS != tt.pps[i] {
+					t.Errorf("read %d: %v pps, want %v", i+2, s.PPS, tt.pps[i])
+				}
+			}
+		})
+	}
+}
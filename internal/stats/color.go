@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"fmt"
+	"os"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// ColorMode is the policy deciding whether output is colored.
+type ColorMode string
+
+const (
+	// ColorAuto colors output going to a terminal, unless NO_COLOR is set.
+	ColorAuto ColorMode = "auto"
+	// ColorAlways colors output wherever it goes.
+	ColorAlways ColorMode = "always"
+	// ColorNever never colors output.
+	ColorNever ColorMode = "never"
+)
+
+// ParseColorMode parses a color policy, the empty name being ColorAuto.
+func ParseColorMode(name string) (ColorMode, error) {
+	switch m := ColorMode(name); m {
+	case "":
+		return ColorAuto, nil
+	case ColorAuto, ColorAlways, ColorNever:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid color mode %q: expected auto, always or never", name)
+	}
+}
+
+// Enabled reports whether output going to a terminal, or not when tty is
+// false, is colored. ColorAuto honors NO_COLOR (https://no-color.org).
+func (m ColorMode) Enabled(tty bool) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	default:
+		return tty && os.Getenv("NO_COLOR") == ""
+	}
+}
+
+// color returns c, or the terminal's default color with NoColor
+func (o *Options) color(c ui.Color) ui.Color {
+	if o.NoColor {
+		return ui.ColorClear
+	}
+
+	return c
+}
+
+// alertStyle is the style of rows needing attention: red, or with A11y
+// reversed video, which reads the same without color
+func (o *Options) alertStyle() ui.Style {
+	if o.A11y {
+		return ui.NewStyle(ui.ColorClear, ui.ColorClear, ui.ModifierReverse|ui.ModifierBold)
+	}
+
+	return ui.NewStyle(o.color(ui.ColorRed), ui.ColorClear, ui.ModifierBold)
+}
+
+// alert marks the action of a row needing attention with a "!" under A11y,
+// so that the alert doesn't depend on the row's color
+func (o *Options) alert(action string) string {
+	if o.A11y {
+		return "! " + action
+	}
+
+	return action
+}
+
+// severityStyle returns the style of a row by the traffic of its action:
+// aborts in red and drops in yellow while they count packets, passes in
+// green. Quiet rows, those of the other actions and those of the groups keep
+// the table's style.
+func (o *Options) severityStyle(s *stats) (ui.Style, bool) {
+	const (
+		abort = 0 // XDP_ABORTED
+		drop  = 1 // XDP_DROP
+		pass  = 2 // XDP_PASS
+	)
+
+	if o.NoColor || s.pps <= 0 {
+		return ui.Style{}, false
+	}
+
+	var c ui.Color
+	switch s.action {
+	case o.ActionNames.name(abort):
+		c = ui.ColorRed
+	case o.ActionNames.name(drop):
+		c = ui.ColorYellow
+	case o.ActionNames.name(pass):
+		c = ui.ColorGreen
+	default:
+		return ui.Style{}, false
+	}
+
+	return ui.NewStyle(c), true
+}
@@ -0,0 +1,146 @@
+package stats
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cilium/ebpf"
+)
+
+// CompositeKeySize is the key size of maps keyed by KeyLayout rather than by
+// the action alone.
+const CompositeKeySize = 8
+
+// KeyLayout encodes and decodes the keys of maps keyed by the XDP action and a
+// sub key, such as the IP protocol, that the counters are broken down by.
+type KeyLayout interface {
+	// EncodeKey returns the map key of action and sub.
+	EncodeKey(action, sub uint32) []byte
+	// DecodeKey splits a map key into its action and sub key.
+	DecodeKey(key []byte) (action, sub uint32, err error)
+	// SubName returns the name shown for a sub key.
+	SubName(sub uint32) string
+}
+
+// ActionProtoKey is the layout of struct { __u32 action; __u32 proto; }, a key
+// breaking the actions down by IP protocol.
+type ActionProtoKey struct{}
+
+func (ActionProtoKey) EncodeKey(action, sub uint32) []byte {
+	key := make([]byte, CompositeKeySize)
+	binary.LittleEndian.PutUint32(key[0:], action)
+	binary.LittleEndian.PutUint32(key[4:], sub)
+
+	return key
+}
+
+func (ActionProtoKey) DecodeKey(key []byte) (uint32, uint32, error) {
+	if len(key) != CompositeKeySize {
+		return 0, 0, fmt.Errorf("unexpected key size %d, expected %d", len(key), CompositeKeySize)
+	}
+
+	return binary.LittleEndian.Uint32(key[0:]), binary.LittleEndian.Uint32(key[4:]), nil
+}
+
+var ipProtocols = map[uint32]string{
+	1:   "icmp",
+	2:   "igmp",
+	6:   "tcp",
+	17:  "udp",
+	47:  "gre",
+	50:  "esp",
+	58:  "ipv6-icmp",
+	132: "sctp",
+}
+
+func (ActionProtoKey) SubName(sub uint32) string {
+	if name, ok := ipProtocols[sub]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("proto %d", sub)
+}
+
+// KeyIterator is the subset of *ebpf.Map needed to list the keys of a
+// composite map.
+type KeyIterator interface {
+	MapLooker
+	NextKey(key, nextKeyOut interface{}) error
+}
+
+// CompositeSources returns one source per sub key present in m, a map keyed
+// by layout, each reading the counters of the actions for that sub key. The
+// sources are named after the sub keys, prefixed by name if it is set. Sub
+// keys first seen after this is called are not shown.
+func CompositeSources(m KeyIterator, layout KeyLayout, name string) ([]Source, error) {
+	seen := make(map[uint32]bool)
+
+	var key interface{}
+
+	for {
+		next := make([]byte, CompositeKeySize)
+
+		err := m.NextKey(key, &next)
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error listing keys: %w", err)
+		}
+
+		_, sub, err := layout.DecodeKey(next)
+		if err != nil {
+			return nil, err
+		}
+
+		seen[sub] = true
+		key = next
+	}
+
+	subs := make([]uint32, 0, len(seen))
+	for sub := range seen {
+		subs = append(subs, sub)
+	}
+
+	sort.Slice(subs, func(i, j int) bool { return subs[i] < subs[j] })
+
+	sources := make([]Source, 0, len(subs))
+
+	for _, sub := range subs {
+		srcName := layout.SubName(sub)
+		if name != "" {
+			srcName = name + " " + srcName
+		}
+
+		sources = append(sources, Source{Name: srcName, Map: &compositeView{m: m, layout: layout, sub: sub}})
+	}
+
+	return sources, nil
+}
+
+// compositeView reads the counters of one sub key of a composite map as if
+// the map were keyed by action alone
+type compositeView struct {
+	m      MapLooker
+	layout KeyLayout
+	sub    uint32
+}
+
+func (c *compositeView) Lookup(key, valueOut interface{}) error {
+	action, ok := key.(*uint32)
+	if !ok {
+		return fmt.Errorf("unexpected key type %T", key)
+	}
+
+	err := c.m.Lookup(c.layout.EncodeKey(*action, c.sub), valueOut)
+
+	// not every action sees every sub key, those that don't count nothing
+	if errors.Is(err, ebpf.ErrKeyNotExist) {
+		return nil
+	}
+
+	return err
+}
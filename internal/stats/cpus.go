@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CPUSet is the CPUs whose per-CPU values are summed, in ascending order. A
+// nil set sums every CPU.
+type CPUSet []int
+
+// ParseCPUSet parses a comma separated list of CPUs and inclusive ranges of
+// CPUs, e.g. "0-7,12".
+func ParseCPUSet(spec string) (CPUSet, error) {
+	seen := make(map[int]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+
+		first, err := strconv.ParseUint(lo, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %q is not a cpu", spec, lo)
+		}
+
+		last, err := strconv.ParseUint(hi, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %q is not a cpu", spec, hi)
+		}
+
+		if last < first {
+			return nil, fmt.Errorf("invalid cpu list %q: range %s is reversed", spec, part)
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			seen[int(cpu)] = true
+		}
+	}
+
+	set := make(CPUSet, 0, len(seen))
+	for cpu := range seen {
+		set = append(set, cpu)
+	}
+
+	sort.Ints(set)
+
+	return set, nil
+}
+
+// pick returns the values of the CPUs in the set, all of them if it is nil
+func (c CPUSet) pick(perCPU []datarec) ([]datarec, error) {
+	// keys missing from composite maps have no values at all
+	if c == nil || len(perCPU) == 0 {
+		return perCPU, nil
+	}
+
+	values := make([]datarec, 0, len(c))
+
+	for _, cpu := range c {
+		if cpu >= len(perCPU) {
+			return nil, fmt.Errorf("cpu %d is out of range, the map holds values for %d cpus", cpu, len(perCPU))
+		}
+
+		values = append(values, perCPU[cpu])
+	}
+
+	return values, nil
+}
+
+// sumValues adds up values
+func sumValues(values []datarec) datarec {
+	var sum datarec
+	for _, d := range values {
+		sum.add(d)
+	}
+
+	return sum
+}
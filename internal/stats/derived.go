@@ -0,0 +1,334 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Derived is a user-defined metric computed on every tick from the samples
+// of several actions, e.g. "drop_ratio=drop.pps/(pass.pps+drop.pps)".
+type Derived struct {
+	Name string
+
+	expr expr
+}
+
+// ParseDerived parses a derived metric of the form name=expression. The
+// expression combines numbers and action.field references with + - * / and
+// parentheses. Actions are given as for groups, fields as for filters
+// (packets, pps, bytes, bps and period). Actions read from several maps are
+// summed.
+func ParseDerived(spec string, names ActionNames) (Derived, error) {
+	name, text, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+
+	if !ok || name == "" || strings.TrimSpace(text) == "" {
+		return Derived{}, fmt.Errorf("invalid derived metric %q: expected name=expression", spec)
+	}
+
+	p := &exprParser{text: text, names: names}
+
+	e, err := p.parse()
+	if err != nil {
+		return Derived{}, fmt.Errorf("invalid derived metric %q: %w", spec, err)
+	}
+
+	return Derived{Name: name, expr: e}, nil
+}
+
+// ParseRatio parses a ratio of the rates of two actions, e.g. "tx/pass", as a
+// derived metric named after the spec. Actions are given as for groups, and
+// "total" stands for the sum of every action. The ratio shows as missing
+// while the divisor has no traffic.
+func ParseRatio(spec string, names ActionNames) (Derived, error) {
+	num, den, ok := strings.Cut(spec, "/")
+	if !ok || strings.TrimSpace(num) == "" || strings.TrimSpace(den) == "" {
+		return Derived{}, fmt.Errorf("invalid ratio %q: expected action/action", spec)
+	}
+
+	var terms [2]expr
+
+	for i, side := range []string{num, den} {
+		e, err := ratioTerm(side, names)
+		if err != nil {
+			return Derived{}, fmt.Errorf("invalid ratio %q: %w", spec, err)
+		}
+
+		terms[i] = e
+	}
+
+	return Derived{Name: strings.TrimSpace(spec), expr: binaryOp{op: '/', left: terms[0], right: terms[1]}}, nil
+}
+
+// ratioTerm returns the pps of an action, or of all of them for "total"
+func ratioTerm(name string, names ActionNames) (expr, error) {
+	if !strings.EqualFold(strings.TrimSpace(name), "total") {
+		act, err := names.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return ref{action: act, field: "pps"}, nil
+	}
+
+	var sum expr = ref{action: 0, field: "pps"}
+	for act := uint(1); act < NumActions; act++ {
+		sum = binaryOp{op: '+', left: sum, right: ref{action: act, field: "pps"}}
+	}
+
+	return sum, nil
+}
+
+// eval computes the metric over the samples of a tick, NaN if it divides by
+// zero
+func (d Derived) eval(samples []Sample, names ActionNames) float64 {
+	return d.expr.eval(samples, names)
+}
+
+type expr interface {
+	eval(samples []Sample, names ActionNames) float64
+}
+
+type number float64
+
+func (n number) eval([]Sample, ActionNames) float64 { return float64(n) }
+
+// ref is a field of an action, summed over the maps
+type ref struct {
+	action uint
+	field  string
+}
+
+func (r ref) eval(samples []Sample, names ActionNames) float64 {
+	var (
+		name = names.name(r.action)
+		sum  float64
+	)
+
+	for _, s := range samples {
+		if s.Action == name {
+			sum += sampleFields[r.field](s)
+		}
+	}
+
+	return sum
+}
+
+type binaryOp struct {
+	op          byte
+	left, right expr
+}
+
+func (b binaryOp) eval(samples []Sample, names ActionNames) float64 {
+	l, r := b.left.eval(samples, names), b.right.eval(samples, names)
+
+	switch b.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	default:
+		if r == 0 {
+			return math.NaN()
+		}
+
+		return l / r
+	}
+}
+
+type negate struct{ e expr }
+
+func (n negate) eval(samples []Sample, names ActionNames) float64 {
+	return -n.e.eval(samples, names)
+}
+
+// exprParser is a recursive descent parser of
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = number | action "." field | "(" expr ")" | "-" factor
+type exprParser struct {
+	text  string
+	pos   int
+	names ActionNames
+}
+
+func (p *exprParser) parse() (expr, error) {
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skipSpace(); p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q", p.text[p.pos:])
+	}
+
+	return e, nil
+}
+
+func (p *exprParser) expr() (expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.op("+-")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryOp{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) term() (expr, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.op("*/")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryOp{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) factor() (expr, error) {
+	p.skipSpace()
+
+	if p.pos >= len(p.text) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	switch c := p.text[p.pos]; {
+	case c == '(':
+		p.pos++
+
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := p.op(")"); !ok {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+
+		return e, nil
+	case c == '-':
+		p.pos++
+
+		e, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		return negate{e}, nil
+	case c == '.' || unicode.IsDigit(rune(c)):
+		return p.number()
+	case c == '_' || unicode.IsLetter(rune(c)):
+		return p.ref()
+	default:
+		return nil, fmt.Errorf("unexpected %q", p.text[p.pos:])
+	}
+}
+
+func (p *exprParser) number() (expr, error) {
+	start := p.pos
+	for p.pos < len(p.text) && strings.ContainsRune("0123456789.eE", rune(p.text[p.pos])) {
+		p.pos++
+
+		// the sign of an exponent, as in 1e-3, is part of the number
+		if c := p.text[p.pos-1]; (c == 'e' || c == 'E') && p.pos < len(p.text) && (p.text[p.pos] == '+' || p.text[p.pos] == '-') {
+			p.pos++
+		}
+	}
+
+	v, err := strconv.ParseFloat(p.text[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", p.text[start:p.pos])
+	}
+
+	return number(v), nil
+}
+
+func (p *exprParser) ref() (expr, error) {
+	start := p.pos
+	for p.pos < len(p.text) && (p.text[p.pos] == '_' || p.text[p.pos] == '.' ||
+		unicode.IsLetter(rune(p.text[p.pos])) || unicode.IsDigit(rune(p.text[p.pos]))) {
+		p.pos++
+	}
+
+	word := p.text[start:p.pos]
+
+	action, field, ok := strings.Cut(word, ".")
+	if !ok {
+		return nil, fmt.Errorf("invalid reference %q: expected action.field", word)
+	}
+
+	act, err := p.names.lookup(action)
+	if err != nil {
+		return nil, err
+	}
+
+	field = strings.ToLower(field)
+	if _, ok := sampleFields[field]; !ok {
+		return nil, fmt.Errorf("invalid reference %q: unknown field %q (known fields: %s)", word, field, strings.Join(filterFields(), ", "))
+	}
+
+	return ref{action: act, field: field}, nil
+}
+
+// op consumes the next operator if it is one of ops
+func (p *exprParser) op(ops string) (byte, bool) {
+	p.skipSpace()
+
+	if p.pos < len(p.text) && strings.IndexByte(ops, p.text[p.pos]) >= 0 {
+		p.pos++
+		return p.text[p.pos-1], true
+	}
+
+	return 0, false
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// derivedRows returns the rows of the derived metrics table for the samples
+// of a tick
+func (o *Options) derivedRows(samples []Sample) [][]string {
+	rows := [][]string{{"Metric", "Value"}}
+
+	for _, d := range o.Derived {
+		v := "-"
+		if x := d.eval(samples, o.ActionNames); !math.IsNaN(x) && !math.IsInf(x, 0) {
+			v = formatAuto(x)
+		}
+
+		rows = append(rows, []string{d.Name, v})
+	}
+
+	return rows
+}
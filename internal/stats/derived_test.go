@@ -0,0 +1,38 @@
+package stats
+
+import "testing"
+
+func TestParseDerived(t *testing.T) {
+	samples := []Sample{{Action: "XDP_DROP", PPS: 500}, {Action: "XDP_PASS", PPS: 1500}}
+
+	tests := []struct {
+		spec string
+		want float64
+	}{
+		{"x=drop.pps", 500},
+		{"x=drop.pps/(pass.pps+drop.pps)", 0.25},
+		{"x=-drop.pps+1000", 500},
+		{"x=drop.pps*1e-3", 0.5},
+		{"x=drop.pps/2.5E+2", 2},
+		{"x=drop.pps*1e3", 500000},
+		{"x=drop.pps - 1e+2 - 100", 300},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDerived(tt.spec, ActionNames{})
+		if err != nil {
+			t.Errorf("ParseDerived(%q): %v", tt.spec, err)
+			continue
+		}
+
+		if got := d.eval(samples, ActionNames{}); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"x=", "x=drop", "x=1e", "x=1e-", "x=drop.pps*(2"} {
+		if _, err := ParseDerived(spec, ActionNames{}); err == nil {
+			t.Errorf("ParseDerived(%q) didn't fail", spec)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"fmt"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// efficiencyGauge shows the bytes passed as a share of the bytes passed and
+// dropped, over the last tick and since the session started
+type efficiencyGauge struct {
+	*widgets.Gauge
+}
+
+func newEfficiencyGauge(opts *Options) *efficiencyGauge {
+	g := &efficiencyGauge{Gauge: widgets.NewGauge()}
+
+	g.Title = " Byte efficiency "
+	g.BarColor = opts.color(ui.ColorGreen)
+	g.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+	g.LabelStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+	g.Label = "no bytes passed or dropped yet"
+
+	return g
+}
+
+// update takes the efficiency of the tick from prev to records and that of
+// the session from baseline to records
+func (g *efficiencyGauge) update(baseline, prev, records []StatsRecord) {
+	tick, ok := byteEfficiency(prev, records)
+	session, sessionOK := byteEfficiency(baseline, records)
+
+	if !ok && !sessionOK {
+		return
+	}
+
+	label := "none passed or dropped this tick"
+	if ok {
+		g.Percent = int(tick + 0.5)
+		label = fmt.Sprintf("%.1f%% of the bytes passed this tick", tick)
+	}
+
+	if sessionOK {
+		label += fmt.Sprintf(", %.1f%% since the start", session)
+	}
+
+	g.Label = label
+}
+
+// byteEfficiency returns the bytes passed as a percentage of the bytes
+// passed and dropped between the records of from and to, and false if there
+// were none
+func byteEfficiency(from, to []StatsRecord) (float64, bool) {
+	const (
+		drop = 1 // XDP_DROP
+		pass = 2 // XDP_PASS
+	)
+
+	var passed, dropped uint64
+	for i := range to {
+		if i >= len(from) {
+			break
+		}
+
+		passed += sinceZero(to[i].record(pass).total.rxBytes, from[i].record(pass).total.rxBytes)
+		dropped += sinceZero(to[i].record(drop).total.rxBytes, from[i].record(drop).total.rxBytes)
+	}
+
+	if passed+dropped == 0 {
+		return 0, false
+	}
+
+	return float64(passed) / float64(passed+dropped) * 100, true
+}
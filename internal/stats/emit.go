@@ -0,0 +1,63 @@
+package stats
+
+import "time"
+
+// emitWindows aggregates the samples of every series over EmitInterval,
+// returning one sample per series whose window closed on this tick, its
+// rates taken over the whole window. Ticks closing no window return nothing.
+func (o *Options) emitWindows(samples []Sample) []Sample {
+	if o.EmitInterval <= 0 {
+		return samples
+	}
+
+	if o.emitStart == nil {
+		o.emitStart = make(map[string]Sample)
+	}
+
+	// a window closes on the tick nearest its end rather than the one after
+	span := o.EmitInterval - o.interval()/2
+
+	var emitted []Sample
+
+	for _, s := range samples {
+		key := s.Map + "\x00" + s.Label + "\x00" + s.Action
+
+		start, ok := o.emitStart[key]
+		if !ok {
+			o.emitStart[key] = s
+			continue
+		}
+
+		elapsed := s.Timestamp.Sub(start.Timestamp)
+		if elapsed < span {
+			continue
+		}
+
+		o.emitStart[key] = s
+
+		// a counter reset leaves the rates of the last tick alone to go by
+		if s.Packets < start.Packets || s.Bytes < start.Bytes || elapsed <= 0 {
+			emitted = append(emitted, s)
+			continue
+		}
+
+		secs := elapsed.Seconds()
+
+		s.PPS = float64(s.Packets-start.Packets) / secs
+		s.BPS = float64(s.Bytes-start.Bytes) * 8 / secs
+		s.Period = secs
+
+		emitted = append(emitted, s)
+	}
+
+	return emitted
+}
+
+// interval returns the time between two ticks, leaving out the jitter
+func (o *Options) interval() time.Duration {
+	if o.Interval <= 0 {
+		return DefaultInterval
+	}
+
+	return o.Interval
+}
@@ -0,0 +1,172 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execGrace is how long Close waits for a running command before killing it
+const execGrace = 5 * time.Second
+
+// execSink runs a command on every tick, handing it the samples of the tick
+// on stdin. See NewExecSink for the contract.
+type execSink struct {
+	command string
+
+	// batch holds the JSON lines of the tick being written
+	batch bytes.Buffer
+	enc   *json.Encoder
+
+	// running is set while a run is in flight, its exit status being sent
+	// on result
+	running bool
+	cmd     *exec.Cmd
+	result  chan error
+
+	failed, skipping bool
+	skipped          int
+}
+
+func newExecSinkFactory(command string) (OutputSink, error) {
+	return NewExecSink(command)
+}
+
+// NewExecSink returns a sink running command through /bin/sh -c once per
+// tick. The command gets the samples of the tick on stdin as newline
+// delimited JSON, the same lines as the json sink writes, followed by EOF.
+// Its stdout and stderr go to stderr, stdout carrying the samples of the
+// session, and NSTATS_SAMPLES in its environment holds the number of lines.
+//
+// Runs never overlap: a tick finishing while the command of an earlier tick
+// still runs is skipped rather than queued, so a slow command doesn't hold
+// up sampling or pile up processes. A command exiting non-zero is logged,
+// the first of a run of failures only, and the next tick runs it again.
+// Close waits a few seconds for a command still running, then kills it.
+func NewExecSink(command string) (OutputSink, error) {
+	if err := checkExecCommand(command); err != nil {
+		return nil, err
+	}
+
+	e := &execSink{command: command}
+	e.enc = json.NewEncoder(&e.batch)
+
+	return e, nil
+}
+
+// checkExecCommand checks that there is a command to run
+func checkExecCommand(command string) error {
+	if strings.TrimSpace(command) == "" {
+		return fmt.Errorf("missing command, expected exec:<command>")
+	}
+
+	return nil
+}
+
+func (e *execSink) Write(s Sample) error {
+	return e.enc.Encode(s)
+}
+
+// Flush runs the command with the tick's batch, unless the previous run is
+// still going.
+func (e *execSink) Flush() error {
+	if e.batch.Len() == 0 {
+		return nil
+	}
+
+	input := append([]byte(nil), e.batch.Bytes()...)
+	lines := bytes.Count(input, []byte("\n"))
+	e.batch.Reset()
+
+	if e.running {
+		select {
+		case err := <-e.result:
+			e.finished(err)
+		default:
+			e.skipped++
+			warn(warnExecSkipped, 1)
+
+			if !e.skipping {
+				e.skipping = true
+				log.Printf("exec: %q is still running, skipping ticks until it is done\n", e.command)
+			}
+
+			return nil
+		}
+	}
+
+	e.skipping = false
+
+	cmd := exec.Command("/bin/sh", "-c", e.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), fmt.Sprintf("NSTATS_SAMPLES=%d", lines))
+
+	if err := cmd.Start(); err != nil {
+		e.finished(err)
+		return nil
+	}
+
+	e.running = true
+	e.cmd = cmd
+	e.result = make(chan error, 1)
+
+	go func(result chan<- error) { result <- cmd.Wait() }(e.result)
+
+	return nil
+}
+
+// finished records the outcome of a run, logging the first of a run of
+// failures
+func (e *execSink) finished(err error) {
+	e.running = false
+
+	if err != nil {
+		warn(warnExecFailure, 1)
+	}
+
+	switch {
+	case err != nil && !e.failed:
+		log.Printf("exec: %q failed, logging again once it succeeds: %v\n", e.command, err)
+	case err == nil && e.failed:
+		log.Printf("exec: %q succeeds again\n", e.command)
+	}
+
+	e.failed = err != nil
+}
+
+// Close runs the command a last time with what is left of the tick, then
+// waits for it, killing it if it takes longer than execGrace.
+func (e *execSink) Close() error {
+	if e.running {
+		e.wait()
+	}
+
+	e.Flush()
+
+	if e.running {
+		e.wait()
+	}
+
+	if e.skipped > 0 {
+		log.Printf("exec: %d ticks were skipped while %q ran\n", e.skipped, e.command)
+	}
+
+	return nil
+}
+
+func (e *execSink) wait() {
+	select {
+	case err := <-e.result:
+		e.finished(err)
+	case <-time.After(execGrace):
+		e.cmd.Process.Kill()
+		e.finished(<-e.result)
+	}
+}
@@ -0,0 +1,33 @@
+package stats
+
+import (
+	"context"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// spyScreen is a screen drawing nowhere, so the render loop runs without a
+// terminal
+type spyScreen struct {
+	events <-chan ui.Event
+
+	renders int
+	closed  bool
+}
+
+func (s *spyScreen) Init() error                 { return nil }
+func (s *spyScreen) Close()                      { s.closed = true }
+func (s *spyScreen) Render(items ...ui.Drawable) { s.renders++ }
+func (s *spyScreen) Clear()                      {}
+func (s *spyScreen) Size() (int, int)            { return 120, 40 }
+func (s *spyScreen) Events() <-chan ui.Event     { return s.events }
+
+// RenderWithEvents runs the render loop of RenderStats on a screen drawing
+// nowhere, reading its keys from events, and returns how many times the
+// screen was drawn and whether it was closed.
+func RenderWithEvents(ctx context.Context, statsMap MapLooker, opts Options, events <-chan ui.Event) (renders int, closed bool, err error) {
+	scr := &spyScreen{events: events}
+	err = renderMaps(ctx, []Source{{Map: statsMap}}, opts, scr)
+
+	return scr.renders, scr.closed, err
+}
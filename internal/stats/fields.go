@@ -0,0 +1,164 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sampleField is a field of Sample as the structured outputs and Schema write
+// it, read from its json and desc tags
+type sampleField struct {
+	name, desc string
+	typ        reflect.Type
+	index      int
+
+	// omitEmpty leaves the field out of JSON when it is the zero value
+	omitEmpty bool
+}
+
+// value returns the value of the field in s
+func (f sampleField) value(s Sample) interface{} {
+	return reflect.ValueOf(s).Field(f.index).Interface()
+}
+
+// fields are the fields of Sample in the order they are declared, which is
+// the order every structured output writes them. New fields are only ever
+// appended to the struct, and names only change along with SchemaVersion.
+var fields = structFields()
+
+// structFields returns the fields of Sample having a json name, in order
+func structFields() []sampleField {
+	t := reflect.TypeOf(Sample{})
+	fields := make([]sampleField, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, tagOpts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		fields = append(fields, sampleField{
+			name:      name,
+			desc:      f.Tag.Get("desc"),
+			typ:       f.Type,
+			index:     i,
+			omitEmpty: tagOpts == "omitempty",
+		})
+	}
+
+	return fields
+}
+
+// SampleFields returns the names of the sample fields in the order the JSON
+// and CSV outputs write them. The order is stable: fields are only added at
+// the end.
+func SampleFields() []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+
+	return names
+}
+
+// MarshalJSON writes the fields of s in the documented order of
+// SampleFields.
+func (s Sample) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for _, f := range fields {
+		v := f.value(s)
+		if f.omitEmpty && isZero(v) {
+			continue
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(strconv.Quote(f.name))
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case map[string]uint64:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// csvHeader names the columns after the sample fields, in order
+func csvHeader() []string {
+	return SampleFields()
+}
+
+func csvRecord(s Sample, format SinkFormat) []string {
+	record := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		if v, ok := format.csvValue(f.name, s); ok {
+			record = append(record, v)
+			continue
+		}
+
+		switch v := f.value(s).(type) {
+		case time.Time:
+			record = append(record, v.Format(time.RFC3339Nano))
+		case string:
+			record = append(record, v)
+		case float64:
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		case map[string]uint64:
+			record = append(record, formatCounters(v))
+		default:
+			record = append(record, fmt.Sprint(v))
+		}
+	}
+
+	return record
+}
+
+// formatCounters writes counters as name=value pairs separated by spaces, in
+// name order
+func formatCounters(counters map[string]uint64) string {
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, counters[name]))
+	}
+
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,69 @@
+package stats
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampleFieldsOrder(t *testing.T) {
+	want := []string{"schema_version", "timestamp", "action", "packets", "pps", "bytes", "bps", "period", "label", "elapsed", "map", "counters"}
+
+	if got := SampleFields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SampleFields() = %v, want %v: fields are only ever appended", got, want)
+	}
+
+	if got := csvHeader(); !reflect.DeepEqual(got, want) {
+		t.Errorf("csvHeader() = %v, want %v", got, want)
+	}
+}
+
+func TestSampleJSONFollowsFields(t *testing.T) {
+	s := Sample{SchemaVersion: SchemaVersion, Timestamp: time.Unix(0, 0).UTC(), Action: "XDP_PASS", Packets: 3, Map: "m"}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"schema_version":1,"timestamp":"1970-01-01T00:00:00Z","action":"XDP_PASS","packets":3,"pps":0,"bytes":0,"bps":0,"period":0,"map":"m"}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON = %s, want %s", data, want)
+	}
+
+	if n := len(csvRecord(s, SinkFormat{})); n != len(fields) {
+		t.Errorf("csvRecord has %d columns, want %d", n, len(fields))
+	}
+}
+
+func TestSchemaFollowsFields(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Properties map[string]interface{} `json:"properties"`
+		Required   []string               `json:"required"`
+	}
+
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range fields {
+		if _, ok := schema.Properties[f.name]; !ok {
+			t.Errorf("schema lacks field %s", f.name)
+		}
+	}
+
+	if len(schema.Properties) != len(fields) {
+		t.Errorf("schema has %d properties, want %d", len(schema.Properties), len(fields))
+	}
+
+	if got := strings.Join(schema.Required, ","); got != "schema_version,timestamp,action,packets,pps,bytes,bps,period" {
+		t.Errorf("required fields %s", got)
+	}
+}
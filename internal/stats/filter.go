@@ -0,0 +1,126 @@
+package stats
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sampleFields are the numeric sample fields a filter can compare, by their
+// JSON names.
+var sampleFields = map[string]func(Sample) float64{
+	"packets": func(s Sample) float64 { return float64(s.Packets) },
+	"pps":     func(s Sample) float64 { return s.PPS },
+	"bytes":   func(s Sample) float64 { return float64(s.Bytes) },
+	"bps":     func(s Sample) float64 { return s.BPS },
+	"period":  func(s Sample) float64 { return s.Period },
+}
+
+var (
+	orRe   = regexp.MustCompile(`(?i)\s+or\s+|\|\|`)
+	andRe  = regexp.MustCompile(`(?i)\s+and\s+|&&`)
+	condRe = regexp.MustCompile(`^\s*([A-Za-z_]+)\s*(>=|<=|==|!=|>|<)\s*(\S+)\s*$`)
+)
+
+// Filter is a predicate over samples, parsed from expressions such as
+// "pps>100" or "pps > 100 AND bytes > 0 OR bps >= 1e6". AND binds tighter
+// than OR.
+type Filter struct {
+	// any of the terms must match, a term matches if all its conditions do
+	terms [][]condition
+}
+
+type condition struct {
+	field string
+	op    string
+	value float64
+}
+
+// ParseFilter parses a filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	f := &Filter{}
+
+	for _, term := range orRe.Split(expr, -1) {
+		var conds []condition
+
+		for _, c := range andRe.Split(term, -1) {
+			m := condRe.FindStringSubmatch(c)
+			if m == nil {
+				return nil, fmt.Errorf("invalid filter %q: expected field op number, got %q", expr, strings.TrimSpace(c))
+			}
+
+			field := strings.ToLower(m[1])
+			if _, ok := sampleFields[field]; !ok {
+				return nil, fmt.Errorf("invalid filter %q: unknown field %q (known fields: %s)", expr, m[1], strings.Join(filterFields(), ", "))
+			}
+
+			v, err := strconv.ParseFloat(m[3], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %q: %q is not a number", expr, m[3])
+			}
+
+			conds = append(conds, condition{field: field, op: m[2], value: v})
+		}
+
+		f.terms = append(f.terms, conds)
+	}
+
+	return f, nil
+}
+
+// Match reports whether s satisfies the filter. A nil filter matches every
+// sample.
+func (f *Filter) Match(s Sample) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, term := range f.terms {
+		ok := true
+
+		for _, c := range term {
+			if !c.match(sampleFields[c.field](s)) {
+				ok = false
+				break
+			}
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c condition) match(v float64) bool {
+	switch c.op {
+	case ">":
+		return v > c.value
+	case ">=":
+		return v >= c.value
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case "==":
+		return v == c.value
+	case "!=":
+		return v != c.value
+	}
+
+	return false
+}
+
+func filterFields() []string {
+	fields := make([]string, 0, len(sampleFields))
+	for f := range sampleFields {
+		fields = append(fields, f)
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
@@ -0,0 +1,37 @@
+package stats
+
+import "testing"
+
+func TestParseFilter(t *testing.T) {
+	s := Sample{Packets: 10, PPS: 150, Bytes: 1000, BPS: 2e6}
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"pps>100", true},
+		{"PPS > 100", true},
+		{"Pps>100 AND Bytes>0", true},
+		{"pps<100 or BPS >= 1e6", true},
+		{"packets==10 && bytes!=1000", false},
+		{"PACKETS<=9 || pps<150", false},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+
+		if got := f.Match(s); got != tt.match {
+			t.Errorf("%q matches %v, want %v", tt.expr, got, tt.match)
+		}
+	}
+
+	for _, expr := range []string{"", "pps", "rate>1", "pps>x", "pps=>1"} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded", expr)
+		}
+	}
+}
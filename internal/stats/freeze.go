@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// columnWidths keeps the cells of every column at the widest width they had
+// so far, or ColumnWidth if that is wider, so the centered numbers of the
+// table don't shift about as their number of digits changes
+type columnWidths struct {
+	least  int
+	widths []int
+}
+
+// freeze pads the cells of row but the action, right aligning each line of
+// them within the width of their column
+func (c *columnWidths) freeze(row []string) []string {
+	for len(c.widths) < len(row) {
+		c.widths = append(c.widths, c.least)
+	}
+
+	for j := 1; j < len(row); j++ {
+		lines := strings.Split(row[j], "\n")
+
+		for _, line := range lines {
+			if n := utf8.RuneCountInString(line); n > c.widths[j] {
+				c.widths[j] = n
+			}
+		}
+
+		for i, line := range lines {
+			lines[i] = strings.Repeat(" ", c.widths[j]-utf8.RuneCountInString(line)) + line
+		}
+
+		row[j] = strings.Join(lines, "\n")
+	}
+
+	return row
+}
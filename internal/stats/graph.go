@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// graphColors are the colors of the sparklines, in the order of the actions
+var graphColors = []ui.Color{ui.ColorRed, ui.ColorYellow, ui.ColorGreen, ui.ColorBlue, ui.ColorMagenta}
+
+// ParseGraphActions parses the comma separated actions to graph, given as in
+// ParseGroup, "all" standing for every action.
+func ParseGraphActions(spec string, names ActionNames) ([]uint, error) {
+	if strings.EqualFold(strings.TrimSpace(spec), "all") {
+		return nil, nil
+	}
+
+	var actions []uint
+
+	for _, a := range strings.Split(spec, ",") {
+		act, err := names.lookup(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid graph actions %q: %w", spec, err)
+		}
+
+		actions = append(actions, act)
+	}
+
+	return actions, nil
+}
+
+// graph is the sparklines of the packet rates of the actions graphed, one per
+// action, stacked
+type graph struct {
+	*widgets.SparklineGroup
+
+	actions []uint
+}
+
+// newGraph returns the sparklines of the actions of o.GraphActions, or of
+// every action
+func newGraph(o *Options) *graph {
+	g := &graph{actions: o.GraphActions}
+
+	if len(g.actions) == 0 {
+		for act := uint(0); act < NumActions; act++ {
+			g.actions = append(g.actions, act)
+		}
+	}
+
+	lines := make([]*widgets.Sparkline, 0, len(g.actions))
+	for i, act := range g.actions {
+		line := widgets.NewSparkline()
+		line.Title = o.ActionNames.name(act)
+		line.LineColor = o.color(graphColors[i%len(graphColors)])
+		lines = append(lines, line)
+	}
+
+	g.SparklineGroup = widgets.NewSparklineGroup(lines...)
+	g.Title = " Packets Per Sec "
+	g.BorderStyle = ui.NewStyle(o.color(ui.ColorCyan))
+
+	return g
+}
+
+// update draws the packet rates of the snapshots, oldest first, the rates of
+// an action read from several maps being summed. The snapshots that don't fit
+// the width of the graph are left out, the newest being kept.
+func (g *graph) update(o *Options, snaps []Snapshot) {
+	if width := g.Inner.Dx(); width > 0 && len(snaps) > width {
+		snaps = snaps[len(snaps)-width:]
+	}
+
+	for i, act := range g.actions {
+		var (
+			line = g.Sparklines[i]
+			name = o.ActionNames.name(act)
+			peak float64
+		)
+
+		line.Data = line.Data[:0]
+
+		for _, snap := range snaps {
+			var pps float64
+			for _, s := range snap.Samples {
+				if s.Action == name {
+					pps += s.PPS
+				}
+			}
+
+			if pps > peak {
+				peak = pps
+			}
+
+			line.Data = append(line.Data, pps)
+		}
+
+		// an idle action is drawn flat, not scaled by a zero peak
+		line.MaxVal = 0
+		if peak == 0 {
+			line.MaxVal = 1
+		}
+
+		line.Title = name
+		if n := len(line.Data); n > 0 {
+			line.Title = fmt.Sprintf("%s %s pps (peak %s)", name, strings.TrimSpace(formatAuto(line.Data[n-1])),
+				strings.TrimSpace(formatAuto(peak)))
+		}
+	}
+}
+
+// height is the height of the graph: a title row and two rows of bars per
+// sparkline, within the borders
+func (g *graph) height() int {
+	return 3*len(g.Sparklines) + 2
+}
@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Group is a synthetic row summing the totals and rates of a set of actions.
+type Group struct {
+	Name string
+
+	// Actions are the keys of the actions summed, every action the map
+	// holds if empty
+	Actions []uint
+}
+
+// ParseGroup parses a group definition of the form name=action,action,...
+// Actions may be given as in the table ("XDP_PASS"), by their short,
+// case-insensitive names ("pass") or by a label from names.
+func ParseGroup(spec string, names ActionNames) (Group, error) {
+	name, list, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+
+	if !ok || name == "" || strings.TrimSpace(list) == "" {
+		return Group{}, fmt.Errorf("invalid group %q: expected name=action,action,...", spec)
+	}
+
+	g := Group{Name: name}
+
+	for _, a := range strings.Split(list, ",") {
+		act, err := names.lookup(a)
+		if err != nil {
+			return Group{}, fmt.Errorf("invalid group %q: %w", spec, err)
+		}
+
+		g.Actions = append(g.Actions, act)
+	}
+
+	return g, nil
+}
+
+// TotalName is the name of the row of TotalGroup
+const TotalName = "TOTAL"
+
+// TotalGroup returns the group summing every action, the overall load of the
+// interface.
+func TotalGroup() Group {
+	return Group{Name: TotalName}
+}
+
+// sum adds up the samples of the actions in the group, actions being indexed
+// by key
+func (g Group) sum(actions []Sample) Sample {
+	sum := Sample{
+		SchemaVersion: SchemaVersion,
+		Action:        g.Name,
+	}
+
+	keys := g.Actions
+	if len(keys) == 0 {
+		for act := range actions {
+			keys = append(keys, uint(act))
+		}
+	}
+
+	for _, act := range keys {
+		if act >= uint(len(actions)) {
+			continue
+		}
+
+		s := actions[act]
+
+		if s.Timestamp.After(sum.Timestamp) {
+			sum.Timestamp = s.Timestamp
+		}
+
+		if s.Period > sum.Period {
+			sum.Period = s.Period
+		}
+
+		sum.Packets += s.Packets
+		sum.PPS += s.PPS
+		sum.Bytes += s.Bytes
+		sum.BPS += s.BPS
+
+		for name, c := range s.Counters {
+			if sum.Counters == nil {
+				sum.Counters = make(map[string]uint64)
+			}
+
+			sum.Counters[name] += c
+		}
+	}
+
+	return sum
+}
+
+// appendGroups appends one synthetic sample per group to samples
+func appendGroups(samples []Sample, groups []Group) []Sample {
+	actions := samples
+
+	for _, g := range groups {
+		samples = append(samples, g.sum(actions))
+	}
+
+	return samples
+}
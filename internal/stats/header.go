@@ -0,0 +1,34 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// compactHeader is a single borderless line above the table telling what is
+// read, how often and for how long, with CompactHeader
+type compactHeader struct {
+	*widgets.Paragraph
+}
+
+func newCompactHeader(opts *Options) *compactHeader {
+	h := &compactHeader{Paragraph: widgets.NewParagraph()}
+
+	h.Border = false
+	h.TextStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+
+	return h
+}
+
+// update tells the uptime of the session as of now
+func (h *compactHeader) update(opts *Options, now time.Time) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	h.Text = fmt.Sprintf("%s | every %s | up %s", opts.CompactHeader, interval, now.Sub(opts.start).Round(time.Second))
+}
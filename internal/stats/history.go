@@ -0,0 +1,52 @@
+package stats
+
+import "sync"
+
+// History keeps the most recent snapshots of a session, so every consumer of
+// recent samples draws from the same store. It is safe for concurrent use.
+type History struct {
+	mu    sync.Mutex
+	snaps []Snapshot
+	next  int
+	full  bool
+}
+
+// NewHistory returns a history holding up to size snapshots.
+func NewHistory(size int) *History {
+	return &History{snaps: make([]Snapshot, size)}
+}
+
+// Append adds a snapshot, evicting the oldest one once the history is full.
+func (h *History) Append(s Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.snaps[h.next] = s
+	h.next = (h.next + 1) % len(h.snaps)
+
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Last returns up to n of the most recent snapshots, oldest first.
+func (h *History) Last(n int) []Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	size := h.next
+	if h.full {
+		size = len(h.snaps)
+	}
+
+	if n > size {
+		n = size
+	}
+
+	out := make([]Snapshot, 0, n)
+	for i := h.next - n; i < h.next; i++ {
+		out = append(out, h.snaps[(i+len(h.snaps))%len(h.snaps)])
+	}
+
+	return out
+}
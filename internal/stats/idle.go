@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// idleTracker keeps when the packet counter of each action last moved
+type idleTracker struct {
+	last map[string]idleState
+	now  time.Time
+}
+
+type idleState struct {
+	packets uint64
+	changed time.Time
+}
+
+func newIdleTracker() *idleTracker {
+	return &idleTracker{last: make(map[string]idleState)}
+}
+
+// update records the counters of samples read at now. Actions seen for the
+// first time count as changed.
+func (t *idleTracker) update(samples []Sample, now time.Time) {
+	t.now = now
+
+	for _, s := range samples {
+		key := s.Map + "\x00" + s.Action
+
+		if last, ok := t.last[key]; ok && last.packets == s.Packets {
+			continue
+		}
+
+		t.last[key] = idleState{packets: s.Packets, changed: now}
+	}
+}
+
+// idle returns "idle 12s" for an action whose packet counter didn't move on
+// the last update, and "" for one that did
+func (t *idleTracker) idle(s Sample) string {
+	if t == nil {
+		return ""
+	}
+
+	last, ok := t.last[s.Map+"\x00"+s.Action]
+	if !ok || !last.changed.Before(t.now) {
+		return ""
+	}
+
+	return fmt.Sprintf("idle %s", t.now.Sub(last.changed).Round(time.Second))
+}
+
+// idleTooLong records whether samples counted any packets at now, and
+// reports whether none were counted for ExitAfterIdle
+func (o *Options) idleTooLong(samples []Sample, now time.Time) bool {
+	if o.ExitAfterIdle <= 0 {
+		return false
+	}
+
+	if o.active.IsZero() {
+		o.active = o.start
+	}
+
+	for _, s := range samples {
+		if s.PPS > 0 {
+			o.active = now
+			break
+		}
+	}
+
+	return now.Sub(o.active) >= o.ExitAfterIdle
+}
+
+// withTraffic returns the samples whose totals are not zero, those of the
+// actions that never counted a packet being left out
+func withTraffic(samples []Sample) []Sample {
+	kept := make([]Sample, 0, len(samples))
+
+	for _, s := range samples {
+		if s.Packets != 0 || s.Bytes != 0 {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept
+}
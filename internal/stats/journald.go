@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"fmt"
+
+	"github.com/bxffour/nstats/internal/journald"
+)
+
+// journaldSink writes every sample to the systemd journal, with the sample
+// fields as XDP_ fields of the entry
+type journaldSink struct {
+	conn *journald.Conn
+}
+
+func newJournaldSinkFactory(string) (OutputSink, error) {
+	return NewJournaldSink()
+}
+
+// NewJournaldSink returns a sink writing samples to the systemd journal. It
+// fails if journald isn't running.
+func NewJournaldSink() (OutputSink, error) {
+	conn, err := journald.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	return &journaldSink{conn: conn}, nil
+}
+
+func (j *journaldSink) Write(s Sample) error {
+	fields := map[string]string{
+		"XDP_ACTION":  s.Action,
+		"XDP_PACKETS": fmt.Sprint(s.Packets),
+		"XDP_PPS":     fmt.Sprintf("%.0f", s.PPS),
+		"XDP_BYTES":   fmt.Sprint(s.Bytes),
+		"XDP_BPS":     fmt.Sprintf("%.0f", s.BPS),
+		"XDP_PERIOD":  fmt.Sprint(s.Period),
+	}
+
+	if s.Label != "" {
+		fields["XDP_LABEL"] = s.Label
+	}
+
+	if s.Map != "" {
+		fields["XDP_MAP"] = s.Map
+	}
+
+	msg := fmt.Sprintf("%s: %d packets (%.0f pps), %d bytes (%.0f bps)", s.Action, s.Packets, s.PPS, s.Bytes, s.BPS)
+
+	return j.conn.Send(journald.PriInfo, msg, fields)
+}
+
+func (j *journaldSink) Close() error { return j.conn.Close() }
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+)
+
+// actionKey returns the key of action in a map keyed by unsigned integers of
+// size bytes
+func actionKey(action, size uint32) (interface{}, error) {
+	switch size {
+	case 1:
+		return uint8(action), nil
+	case 2:
+		return uint16(action), nil
+	case 4:
+		return action, nil
+	case 8:
+		return uint64(action), nil
+	default:
+		return nil, fmt.Errorf("unsupported key size %d, expected 1, 2, 4 or 8 bytes", size)
+	}
+}
+
+// KeyedMap returns a MapLooker reading the actions of m, a map keyed by
+// unsigned integers of keySize bytes, by the u32 keys the collector looks
+// them up with. Actions missing from a hash count nothing. Keys of other
+// types are passed through untouched.
+func KeyedMap(m MapLooker, keySize uint32) (MapLooker, error) {
+	if _, err := actionKey(0, keySize); err != nil {
+		return nil, err
+	}
+
+	return keyedMap{MapLooker: m, size: keySize}, nil
+}
+
+type keyedMap struct {
+	MapLooker
+	size uint32
+}
+
+func (k keyedMap) Lookup(key, valueOut interface{}) error {
+	action, ok := key.(*uint32)
+	if !ok {
+		return k.MapLooker.Lookup(key, valueOut)
+	}
+
+	sized, _ := actionKey(*action, k.size)
+
+	err := k.MapLooker.Lookup(sized, valueOut)
+	if errors.Is(err, ebpf.ErrKeyNotExist) {
+		return nil
+	}
+
+	return err
+}
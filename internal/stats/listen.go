@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Listen listens on addr, a TCP host:port or a Unix socket given as
+// unix:/path. A socket left behind at the path by a previous run is removed
+// first, and the socket file is removed again once the listener is closed.
+func Listen(addr string) (net.Listener, error) {
+	if err := CheckListenAddr(addr); err != nil {
+		return nil, err
+	}
+
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// net.Listen unlinks the socket file when the listener is closed
+	return net.Listen("unix", path)
+}
+
+// CheckListenAddr checks that addr is an address Listen takes, without
+// listening on it.
+func CheckListenAddr(addr string) error {
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid listen address %q, expected host:port or unix:/path", addr)
+		}
+
+		return nil
+	}
+
+	if path == "" {
+		return fmt.Errorf("missing socket path, expected unix:/path")
+	}
+
+	return nil
+}
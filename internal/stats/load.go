@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+// Errors returned by LoadMap, wrapping the underlying error, so callers can
+// tell the usual failures apart with errors.Is.
+var (
+	// ErrMapNotFound means nothing is pinned at the path.
+	ErrMapNotFound = errors.New("map not found")
+
+	// ErrPermission means the caller may not open the pin or use bpf(2).
+	ErrPermission = errors.New("permission denied")
+
+	// ErrMapTypeMismatch means the pin is not a stats map.
+	ErrMapTypeMismatch = errors.New("not an xdp stats map")
+
+	// ErrNoBPFFS means the pin path is not on a bpf filesystem.
+	ErrNoBPFFS = errors.New("bpffs not mounted")
+)
+
+// LoadMap opens the stats map pinned at path and checks its layout with
+// ValidateMap. It fails with ErrNoBPFFS before trying if the closest
+// directory of path that exists is not on a bpf filesystem.
+func LoadMap(path string, readOnly bool) (*ebpf.Map, *ebpf.MapInfo, error) {
+	if err := checkBPFFS(path); err != nil {
+		return nil, nil, err
+	}
+
+	m, err := ebpf.LoadPinnedMap(path, &ebpf.LoadPinOptions{
+		ReadOnly: readOnly,
+	})
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil, fmt.Errorf("%w: error loading pinned map at %s: %w", ErrMapNotFound, path, err)
+	case errors.Is(err, os.ErrPermission):
+		return nil, nil, fmt.Errorf("%w: error loading pinned map at %s: %w", ErrPermission, path, err)
+	case err != nil:
+		return nil, nil, fmt.Errorf("error loading pinned map at %s: %w", path, err)
+	}
+
+	info, err := m.Info()
+	if err != nil {
+		m.Close()
+		return nil, nil, fmt.Errorf("error getting map info: %w", err)
+	}
+
+	if err := ValidateMap(info); err != nil {
+		m.Close()
+		return nil, nil, fmt.Errorf("map at %s: %w", path, err)
+	}
+
+	return m, info, nil
+}
+
+// LoadMapFD opens the stats map behind fd, a file descriptor inherited from
+// a process holding the map, and checks its layout with ValidateMap. The
+// returned map owns fd.
+func LoadMapFD(fd int) (*ebpf.Map, *ebpf.MapInfo, error) {
+	if fd < 0 {
+		return nil, nil, fmt.Errorf("invalid map fd %d", fd)
+	}
+
+	m, err := ebpf.NewMapFromFD(fd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fd %d is not a usable BPF map: %w", fd, err)
+	}
+
+	info, err := m.Info()
+	if err != nil {
+		m.Close()
+		return nil, nil, fmt.Errorf("error getting info of the map at fd %d: %w", fd, err)
+	}
+
+	if err := ValidateMap(info); err != nil {
+		m.Close()
+		return nil, nil, fmt.Errorf("map at fd %d: %w", fd, err)
+	}
+
+	return m, info, nil
+}
+
+// LoadMapID opens the stats map of the given BPF map ID, as listed by bpftool
+// map or the list command, and checks its layout with ValidateMap.
+func LoadMapID(id ebpf.MapID) (*ebpf.Map, *ebpf.MapInfo, error) {
+	m, err := ebpf.NewMapFromID(id)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil, fmt.Errorf("%w: no map has id %d: %w", ErrMapNotFound, id, err)
+	case errors.Is(err, os.ErrPermission):
+		return nil, nil, fmt.Errorf("%w: error opening map %d: %w", ErrPermission, id, err)
+	case err != nil:
+		return nil, nil, fmt.Errorf("error opening map %d: %w", id, err)
+	}
+
+	info, err := m.Info()
+	if err != nil {
+		m.Close()
+		return nil, nil, fmt.Errorf("error getting info of map %d: %w", id, err)
+	}
+
+	if err := ValidateMap(info); err != nil {
+		m.Close()
+		return nil, nil, fmt.Errorf("map %d: %w", id, err)
+	}
+
+	return m, info, nil
+}
+
+// mapNameLen is the longest map name the kernel keeps, longer names being
+// truncated
+const mapNameLen = 15
+
+// LoadMapName opens the stats map named name among the maps loaded, pinned or
+// not, and checks its layout with ValidateMap. Names longer than the kernel
+// keeps match on their truncated prefix. It fails if no map, or more than
+// one, has the name.
+func LoadMapName(name string) (*ebpf.Map, *ebpf.MapInfo, error) {
+	want := name
+	if len(want) > mapNameLen {
+		want = want[:mapNameLen]
+	}
+
+	var ids []ebpf.MapID
+
+	for id := ebpf.MapID(0); ; {
+		next, err := ebpf.MapGetNextID(id)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+
+		switch {
+		case errors.Is(err, os.ErrPermission):
+			return nil, nil, fmt.Errorf("%w: error listing map IDs: %w", ErrPermission, err)
+		case err != nil:
+			return nil, nil, fmt.Errorf("error listing map IDs: %w", err)
+		}
+
+		id = next
+
+		m, err := ebpf.NewMapFromID(id)
+		if err != nil {
+			// the map went away since it was listed
+			continue
+		}
+
+		info, err := m.Info()
+		m.Close()
+
+		if err == nil && info.Name == want {
+			ids = append(ids, id)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, nil, fmt.Errorf("%w: no map is named %q", ErrMapNotFound, name)
+	case 1:
+		return LoadMapID(ids[0])
+	default:
+		return nil, nil, fmt.Errorf("%d maps are named %q, pick one by id among %v", len(ids), name, ids)
+	}
+}
+
+// LooksLikeStatsMap reports whether a map name suggests a stats map, as a
+// guard against pin paths pointing at an unrelated map of the same layout.
+// Names are empty on kernels that don't report them, which is not held
+// against the map.
+func LooksLikeStatsMap(name string) bool {
+	return name == "" || strings.Contains(strings.ToLower(name), "stats")
+}
+
+// checkBPFFS checks that the closest existing directory above path is on a
+// bpf filesystem, where pins live. Errors other than the missing mount are
+// left for the load to report.
+func checkBPFFS(path string) error {
+	dir := filepath.Dir(path)
+
+	var fs unix.Statfs_t
+	for {
+		err := unix.Statfs(dir, &fs)
+		if err == nil {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, unix.ENOENT) || parent == dir {
+			return nil
+		}
+
+		dir = parent
+	}
+
+	if fs.Type != unix.BPF_FS_MAGIC {
+		return fmt.Errorf("%w at %s; run mount -t bpf bpf %s", ErrNoBPFFS, dir, dir)
+	}
+
+	return nil
+}
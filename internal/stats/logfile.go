@@ -0,0 +1,162 @@
+package stats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the suffixes ParseSize takes, with their multiples
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes, optionally followed by a unit, e.g. 512,
+// 100MiB, 10M or 1GB. The single letter units K, M and G are binary.
+func ParseSize(spec string) (int64, error) {
+	s := strings.TrimSpace(spec)
+	multiple := int64(1)
+
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, multiple = strings.TrimSpace(n), u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(s, 10, 63)
+	if err != nil || n == 0 || int64(n) > (1<<63-1)/multiple {
+		return 0, fmt.Errorf("invalid size %q, expected a positive number of bytes, e.g. 100MiB", spec)
+	}
+
+	return int64(n) * multiple, nil
+}
+
+// logSink appends the samples to a CSV file, moving it aside to path.1 once
+// it grows past maxSize and starting a new one. A compressed file grows as
+// its gzip writer is flushed, on every tick.
+type logSink struct {
+	path    string
+	maxSize int64
+
+	// size is the size of the file, counting what was written to it
+	size int64
+	csv  *csvSink
+}
+
+// NewCSVLogSink returns a sink appending the samples to the CSV file at path,
+// as the csv sink does: the file gets a header row only if it is new or
+// empty, and every sample is flushed as it is written. With a maxSize above
+// zero the file is rotated once it grows past maxSize bytes, the full file
+// being renamed to path.1, replacing the previous one, and a new file started
+// with a header of its own. Paths ending in .gz are gzip compressed, maxSize
+// counting the compressed bytes, and the rotated file is closed with its
+// gzip trailer so it reads back whole.
+func NewCSVLogSink(path string, maxSize int64) (OutputSink, error) {
+	if err := ValidateLogFile(path, maxSize); err != nil {
+		return nil, err
+	}
+
+	if maxSize <= 0 {
+		return newCSVFileSink(path)
+	}
+
+	l := &logSink{path: path, maxSize: maxSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// ValidateLogFile checks that NewCSVLogSink takes path and maxSize, without
+// opening the file.
+func ValidateLogFile(path string, maxSize int64) error {
+	if maxSize > 0 && path == "" {
+		return fmt.Errorf("invalid log file %q: only files can be rotated", path)
+	}
+
+	return nil
+}
+
+func (l *logSink) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.size = info.Size()
+
+	file := &sinkFile{Writer: &countingWriter{w: f, n: &l.size}, flush: func() error { return nil }, close: f.Close, empty: l.size == 0}
+	if strings.HasSuffix(l.path, ".gz") {
+		file.compress()
+	}
+
+	l.csv = &csvSink{w: csv.NewWriter(file), header: file.empty, file: file}
+
+	return nil
+}
+
+func (l *logSink) Write(s Sample) error {
+	if err := l.csv.Write(s); err != nil {
+		return err
+	}
+
+	return l.rotateFull()
+}
+
+func (l *logSink) Flush() error {
+	if err := l.csv.Flush(); err != nil {
+		return err
+	}
+
+	return l.rotateFull()
+}
+
+func (l *logSink) Close() error { return l.csv.Close() }
+
+// rotateFull moves the file aside and opens a new one once it has grown
+// past maxSize
+func (l *logSink) rotateFull() error {
+	if l.size < l.maxSize {
+		return nil
+	}
+
+	if err := l.csv.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return fmt.Errorf("error rotating %s: %w", l.path, err)
+	}
+
+	return l.open()
+}
+
+// countingWriter adds the bytes written through it to n
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+
+	return n, err
+}
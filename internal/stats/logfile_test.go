@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readGzipCSV returns the rows of the gzip compressed CSV file at path
+func readGzipCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	rows, err := csv.NewReader(gz).ReadAll()
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	return rows
+}
+
+func TestCSVLogSinkRotatesGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nstats.csv.gz")
+
+	// small enough for the first flushed tick to fill the file
+	sink, err := NewCSVLogSink(path, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1700000000, 0)
+	if err := sink.Write(Sample{Timestamp: start, Action: "XDP_PASS", Packets: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.(flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second sample goes to the new file, closed before it is flushed
+	if err := sink.Write(Sample{Timestamp: start.Add(time.Second), Action: "XDP_PASS", Packets: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, packets := range map[string]string{path + ".1": "1", path: "2"} {
+		rows := readGzipCSV(t, file)
+		if len(rows) != 2 || rows[0][0] != csvHeader()[0] {
+			t.Fatalf("%s: %d rows, want a header and a sample", file, len(rows))
+		}
+
+		if got := rows[1][fieldIndex(t, "packets")]; got != packets {
+			t.Errorf("%s: packets %s, want %s", file, got, packets)
+		}
+	}
+}
+
+// fieldIndex returns the CSV column of the field name
+func fieldIndex(t *testing.T, name string) int {
+	t.Helper()
+
+	for i, h := range csvHeader() {
+		if h == name {
+			return i
+		}
+	}
+
+	t.Fatalf("no %s column", name)
+
+	return -1
+}
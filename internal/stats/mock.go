@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// mockShares are the shares of the traffic of every action in a mock map,
+// and the average packet sizes
+var mockShares = [NumActions]struct {
+	share, size float64
+}{
+	{0.001, 64},  // XDP_ABORT
+	{0.2, 90},    // XDP_DROP
+	{0.7, 800},   // XDP_PASS
+	{0.05, 1200}, // XDP_TX
+	{0.049, 400}, // XDP_REDIRECT
+}
+
+// mockMap is a map of counters growing with synthetic traffic
+type mockMap struct {
+	rate  float64
+	start time.Time
+
+	mu      sync.Mutex
+	actions [NumActions]mockAction
+}
+
+type mockAction struct {
+	packets, bytes float64
+	last           time.Time
+
+	// walk is a random walk scaling the rate, between 0.5 and 1.5
+	walk float64
+}
+
+// NewMockMap returns a map whose counters grow with synthetic traffic of
+// about rate packets per second, split across the actions, each swelling
+// and ebbing on a wave of its own with some noise. It stands in for a stats
+// map to demo the table without eBPF.
+func NewMockMap(rate float64) MapLooker {
+	m := &mockMap{rate: rate, start: time.Now()}
+
+	for i := range m.actions {
+		m.actions[i] = mockAction{last: m.start, walk: 1}
+	}
+
+	return m
+}
+
+func (m *mockMap) Lookup(key, valueOut interface{}) error {
+	k, ok := key.(*uint32)
+	if !ok || *k >= NumActions {
+		return fmt.Errorf("mock map: unexpected key %v", key)
+	}
+
+	out, ok := valueOut.(*[]datarec)
+	if !ok {
+		return fmt.Errorf("mock map: unsupported value type %T", valueOut)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := &m.actions[*k]
+	now := time.Now()
+
+	if dt := now.Sub(a.last).Seconds(); dt > 0 {
+		a.walk = math.Max(0.5, math.Min(1.5, a.walk+(rand.Float64()-0.5)*0.1))
+
+		// a wave of 20 to 60 seconds depending on the action
+		period := 20 + 10*float64(*k)
+		wave := 1 + 0.3*math.Sin(2*math.Pi*now.Sub(m.start).Seconds()/period+float64(*k))
+
+		packets := m.rate * mockShares[*k].share * wave * a.walk * dt
+		a.packets += packets
+		a.bytes += packets * mockShares[*k].size
+		a.last = now
+	}
+
+	*out = []datarec{{rxPackets: uint64(a.packets), rxBytes: uint64(a.bytes)}}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ActionNames maps action keys to the labels shown for them, for programs that
+// give the action indices their own meaning. Keys without a label are shown
+// by their XDP action name.
+type ActionNames map[uint]string
+
+// ParseActionNames parses a comma separated list of key=label pairs, e.g.
+// "0=DENY,1=ALLOW".
+func ParseActionNames(spec string) (ActionNames, error) {
+	names := make(ActionNames)
+
+	for _, pair := range strings.Split(spec, ",") {
+		if err := names.add(pair); err != nil {
+			return nil, err
+		}
+	}
+
+	return names, nil
+}
+
+// LoadActionNames reads key=label pairs from a file, one per line. Blank lines
+// and lines starting with # are ignored.
+func LoadActionNames(path string) (ActionNames, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(ActionNames)
+
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if err := names.add(text); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+
+	return names, sc.Err()
+}
+
+func (n ActionNames) add(pair string) error {
+	key, label, ok := strings.Cut(pair, "=")
+	key, label = strings.TrimSpace(key), strings.TrimSpace(label)
+
+	if !ok || label == "" {
+		return fmt.Errorf("invalid action name %q: expected key=label", pair)
+	}
+
+	k, err := strconv.ParseUint(key, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid action name %q: %q is not an action key", pair, key)
+	}
+
+	n[uint(k)] = label
+
+	return nil
+}
+
+// Validate checks that every key is below entries, the number of keys in the
+// map.
+func (n ActionNames) Validate(entries uint32) error {
+	for k, label := range n {
+		if k >= uint(entries) {
+			return fmt.Errorf("action name %d=%s is out of range, the map has %d entries", k, label, entries)
+		}
+	}
+
+	return nil
+}
+
+// name returns the label of act
+func (n ActionNames) name(act uint) string {
+	if label, ok := n[act]; ok {
+		return label
+	}
+
+	return action2str(act)
+}
+
+// lookup returns the key of an action given by its label or its XDP action
+// name. Labels are matched case-insensitively.
+func (n ActionNames) lookup(name string) (uint, error) {
+	for k, label := range n {
+		if strings.EqualFold(label, strings.TrimSpace(name)) {
+			return k, nil
+		}
+	}
+
+	return str2action(name)
+}
@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseActionOrder parses the order the table shows the actions in, as a
+// comma separated list naming every action once, e.g.
+// "drop,pass,abort,tx,redirect". Actions are named as in ParseGroup.
+func ParseActionOrder(spec string, names ActionNames) ([]uint, error) {
+	var (
+		order []uint
+		seen  = make(map[uint]bool, NumActions)
+	)
+
+	for _, a := range strings.Split(spec, ",") {
+		act, err := names.lookup(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid action order %q: %w", spec, err)
+		}
+
+		if seen[act] {
+			return nil, fmt.Errorf("invalid action order %q: %s is listed twice", spec, names.name(act))
+		}
+
+		seen[act] = true
+		order = append(order, act)
+	}
+
+	for act := uint(0); act < NumActions; act++ {
+		if !seen[act] {
+			return nil, fmt.Errorf("invalid action order %q: %s is missing", spec, names.name(act))
+		}
+	}
+
+	return order, nil
+}
+
+// order puts the actions of every source in ActionOrder, for display, those of
+// a map holding more actions than the order names after them. The groups stay
+// after the actions of their source.
+func (o *Options) order(samples []Sample) []Sample {
+	if len(o.ActionOrder) == 0 {
+		return samples
+	}
+
+	ordered := make([]Sample, 0, len(samples))
+
+	// the samples of a source are next to each other, tagged with its name
+	for start := 0; start < len(samples); {
+		end := start + 1
+		for end < len(samples) && samples[end].Map == samples[start].Map {
+			end++
+		}
+
+		ordered = append(ordered, o.orderSource(samples[start:end])...)
+		start = end
+	}
+
+	return ordered
+}
+
+// orderSource orders the samples of a source, its actions followed by its
+// groups
+func (o *Options) orderSource(samples []Sample) []Sample {
+	n := len(samples) - len(o.Groups)
+	if n < 0 {
+		return samples
+	}
+
+	var (
+		ordered = make([]Sample, 0, len(samples))
+		placed  = make([]bool, n)
+	)
+
+	for _, act := range o.ActionOrder {
+		if int(act) < n {
+			ordered = append(ordered, samples[act])
+			placed[act] = true
+		}
+	}
+
+	for i, s := range samples[:n] {
+		if !placed[i] {
+			ordered = append(ordered, s)
+		}
+	}
+
+	return append(ordered, samples[n:]...)
+}
@@ -0,0 +1,324 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// otlpTimeout bounds every export, and the last one made on Close
+const otlpTimeout = 5 * time.Second
+
+// otlpSink exports the samples as OpenTelemetry metrics over OTLP/HTTP with
+// the JSON encoding, once per tick. The exports are made by a goroutine of
+// its own so a slow collector doesn't hold up sampling; a tick exported
+// while the previous export is still running replaces any batch waiting,
+// which loses nothing as the counters are cumulative.
+type otlpSink struct {
+	endpoint string
+	client   *http.Client
+
+	// start is the start time of the cumulative counters
+	start time.Time
+
+	// batch holds the samples of the tick being written
+	batch []Sample
+
+	pending chan []Sample
+	done    chan struct{}
+
+	failed bool
+}
+
+func newOTLPSinkFactory(endpoint string) (OutputSink, error) {
+	return NewOTLPSink(endpoint)
+}
+
+// NewOTLPSink returns a sink exporting every tick's samples to the OTLP/HTTP
+// collector at endpoint, e.g. http://localhost:4318, posting to its
+// /v1/metrics path unless endpoint has a path of its own. The totals are
+// exported as the cumulative monotonic sums xdp.rx.packets and
+// xdp.rx.bytes, the rates as the gauges xdp.rx.pps and xdp.rx.bps, with the
+// action, label and map as attributes. Failed exports are logged, not
+// returned, so a missing collector doesn't stop the session.
+func NewOTLPSink(endpoint string) (OutputSink, error) {
+	u, err := otlpURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &otlpSink{
+		endpoint: u,
+		client:   &http.Client{Timeout: otlpTimeout},
+		start:    time.Now(),
+		pending:  make(chan []Sample, 1),
+		done:     make(chan struct{}),
+	}
+
+	go o.run()
+
+	return o, nil
+}
+
+// otlpURL returns the URL the metrics are posted to for endpoint
+func otlpURL(endpoint string) (string, error) {
+	if endpoint == "" {
+		return "", fmt.Errorf("missing endpoint, expected otlp:http://host:port")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q, expected http(s)://host:port[/path]", endpoint)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/metrics"
+	}
+
+	return u.String(), nil
+}
+
+func (o *otlpSink) Write(s Sample) error {
+	o.batch = append(o.batch, s)
+	return nil
+}
+
+// Flush hands the tick's batch to the exporting goroutine.
+func (o *otlpSink) Flush() error {
+	if len(o.batch) == 0 {
+		return nil
+	}
+
+	batch := o.batch
+	o.batch = nil
+
+	select {
+	case o.pending <- batch:
+	default:
+		// the exporter is behind: the newer totals supersede the batch waiting
+		select {
+		case <-o.pending:
+		default:
+		}
+
+		o.pending <- batch
+	}
+
+	return nil
+}
+
+func (o *otlpSink) run() {
+	defer close(o.done)
+
+	for batch := range o.pending {
+		o.export(batch)
+	}
+}
+
+// export posts batch, logging the first of a run of failures
+func (o *otlpSink) export(batch []Sample) {
+	body, err := json.Marshal(o.request(batch))
+	if err == nil {
+		err = o.post(body)
+	}
+
+	switch {
+	case err != nil && !o.failed:
+		warn(warnOTLPExport, 1)
+		log.Printf("otlp: exporting metrics failed, dropping them until it recovers: %v\n", err)
+	case err == nil && o.failed:
+		log.Println("otlp: exporting metrics again")
+	}
+
+	o.failed = err != nil
+}
+
+func (o *otlpSink) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector answered %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Close exports what is left of the tick's batch and waits for the exports
+// queued to be made.
+func (o *otlpSink) Close() error {
+	o.Flush()
+	close(o.pending)
+	<-o.done
+
+	return nil
+}
+
+// The OTLP JSON encoding of an ExportMetricsServiceRequest, 64-bit integers
+// being written as strings
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpMetric struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Unit        string     `json:"unit"`
+		Sum         *otlpSum   `json:"sum,omitempty"`
+		Gauge       *otlpGauge `json:"gauge,omitempty"`
+	}
+
+	otlpSum struct {
+		AggregationTemporality int             `json:"aggregationTemporality"`
+		IsMonotonic            bool            `json:"isMonotonic"`
+		DataPoints             []otlpDataPoint `json:"dataPoints"`
+	}
+
+	otlpGauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+
+	otlpDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsInt             string          `json:"asInt,omitempty"`
+		AsDouble          *float64        `json:"asDouble,omitempty"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// otlpCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE
+const otlpCumulative = 2
+
+// request returns the export request of the samples of a tick
+func (o *otlpSink) request(batch []Sample) otlpRequest {
+	var (
+		start = strconv.FormatInt(o.start.UnixNano(), 10)
+
+		packets = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+		bytes   = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+		extra   = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+		pps     = &otlpGauge{}
+		bps     = &otlpGauge{}
+	)
+
+	for _, s := range batch {
+		var (
+			attrs = otlpAttributes(s)
+			now   = strconv.FormatInt(s.Timestamp.UnixNano(), 10)
+			rate  = s.PPS
+			bits  = s.BPS
+		)
+
+		packets.DataPoints = append(packets.DataPoints, otlpDataPoint{Attributes: attrs, StartTimeUnixNano: start,
+			TimeUnixNano: now, AsInt: strconv.FormatUint(s.Packets, 10)})
+		bytes.DataPoints = append(bytes.DataPoints, otlpDataPoint{Attributes: attrs, StartTimeUnixNano: start,
+			TimeUnixNano: now, AsInt: strconv.FormatUint(s.Bytes, 10)})
+		pps.DataPoints = append(pps.DataPoints, otlpDataPoint{Attributes: attrs, TimeUnixNano: now, AsDouble: &rate})
+		bps.DataPoints = append(bps.DataPoints, otlpDataPoint{Attributes: attrs, TimeUnixNano: now, AsDouble: &bits})
+
+		names := make([]string, 0, len(s.Counters))
+		for name := range s.Counters {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			extra.DataPoints = append(extra.DataPoints, otlpDataPoint{
+				Attributes:        append(append([]otlpAttribute(nil), attrs...), otlpAttr("counter", name)),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      now,
+				AsInt:             strconv.FormatUint(s.Counters[name], 10),
+			})
+		}
+	}
+
+	metrics := []otlpMetric{
+		{Name: "xdp.rx.packets", Description: "Packets seen by the XDP program, by action.", Unit: "{packet}", Sum: packets},
+		{Name: "xdp.rx.bytes", Description: "Bytes seen by the XDP program, by action.", Unit: "By", Sum: bytes},
+		{Name: "xdp.rx.pps", Description: "Packets per second over the last period, by action.", Unit: "{packet}/s", Gauge: pps},
+		{Name: "xdp.rx.bps", Description: "Bits per second over the last period, by action.", Unit: "bit/s", Gauge: bps},
+	}
+
+	if len(extra.DataPoints) > 0 {
+		metrics = append(metrics, otlpMetric{Name: "xdp.counter", Description: "Extra counters of the map's value schema, by action and counter.",
+			Unit: "1", Sum: extra})
+	}
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     otlpResource{Attributes: []otlpAttribute{otlpAttr("service.name", "nstats")}},
+		ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: "nstats"}, Metrics: metrics}},
+	}}}
+}
+
+// otlpAttributes returns the attributes of the series of s, always in the
+// same order
+func otlpAttributes(s Sample) []otlpAttribute {
+	attrs := []otlpAttribute{otlpAttr("action", s.Action)}
+
+	if s.Label != "" {
+		attrs = append(attrs, otlpAttr("label", s.Label))
+	}
+
+	if s.Map != "" {
+		attrs = append(attrs, otlpAttr("map", s.Map))
+	}
+
+	return attrs
+}
+
+func otlpAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Stream reads samples from statsMap every opts.Interval and writes them to
+// opts.Sinks, without a terminal UI, until ctx is cancelled, opts.Count ticks
+// are written or the traffic stays idle for opts.ExitAfterIdle.
+func Stream(ctx context.Context, statsMap MapLooker, opts Options) error {
+	return StreamMaps(ctx, []Source{{Map: statsMap}}, opts)
+}
+
+// StreamMaps is Stream reading every source on each tick.
+func StreamMaps(ctx context.Context, sources []Source, opts Options) error {
+	opts.start = opts.clock().Now()
+
+	defer opts.bufferSinks()()
+
+	// stdout carries the samples
+	defer func() {
+		for _, slo := range opts.SLOs {
+			log.Printf("SLO %s\n", slo)
+		}
+	}()
+
+	reader, err := opts.newReader(sources)
+	if err != nil {
+		return err
+	}
+
+	baseline := reader.baseline
+
+	if opts.ZeroOnStart {
+		opts.zero = baseline
+	}
+
+	timer := time.NewTimer(opts.tickInterval())
+	defer timer.Stop()
+
+	annotations := opts.Annotations
+
+	// written is the number of ticks written to the sinks, or of emit
+	// windows with EmitInterval
+	written := 0
+
+	for {
+		select {
+		case a, ok := <-annotations:
+			if !ok {
+				annotations = nil
+				break
+			}
+
+			if err := opts.writeAnnotation(a); err != nil {
+				return err
+			}
+
+		case <-timer.C:
+			timer.Reset(opts.tickInterval())
+
+			recv, samples, err := reader.read()
+			if errors.Is(err, errUnstableRead) {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if opts.idleTooLong(samples, opts.clock().Now()) {
+				// stdout carries the samples
+				log.Printf("No traffic for %s, exiting\n", opts.ExitAfterIdle)
+				log.Println(sessionSummary(baseline, recv, opts.clock().Now().Sub(opts.start)))
+
+				return nil
+			}
+
+			// the rates of the first ticks are left out
+			if opts.clock().Now().Sub(opts.start) < opts.Warmup {
+				continue
+			}
+
+			if opts.History != nil {
+				opts.History.Append(newSnapshot(samples))
+			}
+
+			opts.trackSLOs(samples)
+
+			if err := opts.raiseAlerts(samples); err != nil {
+				return err
+			}
+
+			ok, err := opts.writeSinks(samples)
+			if err != nil {
+				return err
+			}
+
+			// ticks closing no emit window aren't counted
+			if !ok {
+				continue
+			}
+
+			if written++; opts.Count > 0 && written >= opts.Count {
+				return nil
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// peakHold is how long a peak is held before it starts decaying
+	peakHold = 2 * time.Second
+
+	// peakHalfLife is how fast a held peak decays once peakHold is over
+	peakHalfLife = 2 * time.Second
+)
+
+// peakTracker holds the peak rates of every action, like the peak hold of an
+// audio meter, so brief spikes stay readable
+type peakTracker struct {
+	peaks map[string]*peakRates
+}
+
+type peakRates struct {
+	pps, bps heldPeak
+}
+
+// heldPeak is a peak value, decaying from raised once held for peakHold
+type heldPeak struct {
+	value, raised float64
+	at            time.Time
+}
+
+func (p *heldPeak) update(v float64, now time.Time) {
+	if held := now.Sub(p.at); held > peakHold {
+		p.value = p.raised * math.Pow(0.5, float64(held-peakHold)/float64(peakHalfLife))
+	}
+
+	if v >= p.value {
+		p.value, p.raised, p.at = v, v, now
+	}
+}
+
+func newPeakTracker() *peakTracker {
+	return &peakTracker{peaks: make(map[string]*peakRates)}
+}
+
+// update raises the peaks to the rates of samples read at now, decaying the
+// ones held long enough
+func (t *peakTracker) update(samples []Sample, now time.Time) {
+	for _, s := range samples {
+		key := s.Map + "\x00" + s.Action
+
+		p, ok := t.peaks[key]
+		if !ok {
+			p = &peakRates{}
+			t.peaks[key] = p
+		}
+
+		p.pps.update(s.PPS, now)
+		p.bps.update(s.BPS, now)
+	}
+}
+
+// peak returns the peak rates held for the action of s, e.g.
+// "5340 pps, 41 Mbit/s"
+func (t *peakTracker) peak(s Sample) string {
+	if t == nil {
+		return ""
+	}
+
+	p, ok := t.peaks[s.Map+"\x00"+s.Action]
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%.0f pps, %s", p.pps.value, strings.TrimSpace(formatRate(p.bps.value, "bit")))
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// perCPURows returns the rows of the per-CPU table: a row per CPU, a column
+// per action, each cell giving the rates a CPU saw for an action between prev
+// and recv. The CPUs are those of o.CPUs, or every CPU the map holds values
+// for. The number of CPUs may change between the records, CPUs coming online
+// or going offline, so a CPU missing from either record, or whose 64-bit
+// counters went backward, has no rates.
+func (o *Options) perCPURows(prev, recv StatsRecord) [][]string {
+	header := []string{"CPU"}
+	for act := range recv.Records {
+		header = append(header, o.ActionNames.name(uint(act)))
+	}
+
+	rows := [][]string{header}
+
+	for _, cpu := range o.perCPUIndexes(recv) {
+		row := []string{fmt.Sprintf("%d", cpu)}
+
+		for act, rec := range recv.Records {
+			row = append(row, perCPURate(prev.record(act), rec, cpu))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// perCPUIndexes returns the CPUs shown in the per-CPU table
+func (o *Options) perCPUIndexes(recv StatsRecord) []int {
+	if o.CPUs != nil {
+		return o.CPUs
+	}
+
+	n := 0
+	for _, rec := range recv.Records {
+		if len(rec.perCPU) > n {
+			n = len(rec.perCPU)
+		}
+	}
+
+	cpus := make([]int, n)
+	for i := range cpus {
+		cpus[i] = i
+	}
+
+	return cpus
+}
+
+// perCPURate returns the rates of a CPU between two records of an action,
+// e.g. "5340 pps, 41 Mbit/s", or "-" if they don't both hold it
+func perCPURate(prev, recv record, cpu int) string {
+	if cpu >= len(prev.perCPU) || cpu >= len(recv.perCPU) {
+		return "-"
+	}
+
+	before, after := prev.perCPU[cpu], recv.perCPU[cpu]
+	if !narrow(before, after) && (after.rxPackets < before.rxPackets || after.rxBytes < before.rxBytes) {
+		return "-"
+	}
+
+	period := recv.timestamp.Sub(prev.timestamp).Seconds()
+	if period <= 0 {
+		return "-"
+	}
+
+	packets, bytes := valueDelta(before, after)
+	pps := float64(packets) / period
+	bps := computeBitsPerSec(float64(bytes), period)
+
+	return fmt.Sprintf("%.0f pps, %s", pps, strings.TrimSpace(formatRate(bps, "bit")))
+}
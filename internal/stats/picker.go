@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// columnPicker is an overlay listing the columns of the table with a
+// checkbox each, toggled live. It is drawn over the other widgets while
+// open.
+type columnPicker struct {
+	*widgets.List
+
+	table *scrollTable
+	open  bool
+}
+
+func newColumnPicker(table *scrollTable, opts *Options) *columnPicker {
+	p := &columnPicker{List: widgets.NewList(), table: table}
+
+	p.Title = " Columns: space toggles, c closes "
+	p.TextStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+	p.SelectedRowStyle = ui.NewStyle(opts.color(ui.ColorCyan), ui.ColorClear, ui.ModifierBold)
+	p.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+
+	return p
+}
+
+// columns are the columns that can be hidden, all but the action
+func (p *columnPicker) columns() []string {
+	if len(p.table.rows) == 0 {
+		return nil
+	}
+
+	return p.table.rows[0][1:]
+}
+
+func (p *columnPicker) refresh() {
+	p.Rows = p.Rows[:0]
+
+	for _, c := range p.columns() {
+		box := "[x]"
+		if p.table.hidden[c] {
+			box = "[ ]"
+		}
+
+		p.Rows = append(p.Rows, fmt.Sprintf("%s %s", box, strings.ReplaceAll(c, "\n", " / ")))
+	}
+}
+
+// handle handles a key press while the picker is open
+func (p *columnPicker) handle(key string) {
+	switch key {
+	case "<Up>", "k":
+		p.ScrollUp()
+	case "<Down>", "j":
+		p.ScrollDown()
+	case "<Space>", "<Enter>":
+		columns := p.columns()
+		if p.SelectedRow >= len(columns) {
+			break
+		}
+
+		c := columns[p.SelectedRow]
+		if p.table.hidden == nil {
+			p.table.hidden = make(map[string]bool)
+		}
+
+		p.table.hidden[c] = !p.table.hidden[c]
+	case "c", "<Escape>":
+		p.open = false
+	}
+
+	p.refresh()
+}
+
+// toggle opens or closes the picker
+func (p *columnPicker) toggle() {
+	p.open = !p.open
+	p.refresh()
+}
+
+// place centers the picker in a terminal of width by height
+func (p *columnPicker) place(width, height int) {
+	w, h := 40, len(p.columns())+2
+	if w > width {
+		w = width
+	}
+
+	if h > height {
+		h = height
+	}
+
+	x, y := (width-w)/2, (height-h)/2
+	p.SetRect(x, y, x+w, y+h)
+}
+
+func (p *columnPicker) Draw(buf *ui.Buffer) {
+	if !p.open {
+		return
+	}
+
+	// clear what is beneath before drawing the list over it
+	buf.Fill(ui.NewCell(' ', ui.NewStyle(ui.ColorClear)), p.GetRect())
+	p.List.Draw(buf)
+}
@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const procNetDev = "/proc/net/dev"
+
+// readInterfaceCounters returns the receive counters the kernel keeps for
+// iface, as listed in /proc/net/dev.
+func readInterfaceCounters(path, iface string) (datarec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return datarec{}, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		name, counters, ok := strings.Cut(sc.Text(), ":")
+		if !ok || strings.TrimSpace(name) != iface {
+			continue
+		}
+
+		// receive bytes, packets, errs, drop, fifo, frame, compressed,
+		// multicast followed by the transmit counters
+		fields := strings.Fields(counters)
+		if len(fields) < 2 {
+			return datarec{}, fmt.Errorf("malformed %s entry for %s", path, iface)
+		}
+
+		var rec datarec
+
+		if rec.rxBytes, err = strconv.ParseUint(fields[0], 10, 64); err != nil {
+			return datarec{}, fmt.Errorf("malformed %s entry for %s: %w", path, iface, err)
+		}
+
+		if rec.rxPackets, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+			return datarec{}, fmt.Errorf("malformed %s entry for %s: %w", path, iface, err)
+		}
+
+		return rec, nil
+	}
+
+	if err := sc.Err(); err != nil {
+		return datarec{}, err
+	}
+
+	return datarec{}, fmt.Errorf("interface %s not found in %s", iface, path)
+}
+
+// interfaceCounts compares the packets XDP passed to the network stack with
+// those the stack received on the interface, both counted from when the
+// session started. Only XDP_PASS hands packets to the stack: XDP_DROP and
+// XDP_ABORTED drop them and XDP_TX and XDP_REDIRECT send them elsewhere
+// before the stack counts them, so the other actions are left out.
+type interfaceCounts struct {
+	iface string
+
+	// pass and stack are the XDP_PASS total and the counters of the
+	// interface once the interface is first read, which is when started is
+	// set, counting from them
+	started     bool
+	pass, stack datarec
+}
+
+// xdpPass is the key of XDP_PASS
+const xdpPass = 2
+
+// rows returns the rows of the interface table for recv, the last record of
+// the map, and the counters of the interface read with it, or the error
+// reading them
+func (c *interfaceCounts) rows(recv StatsRecord, stack datarec, err error) [][]string {
+	header := []string{"Interface " + c.iface, "Packets", "Bytes"}
+
+	if err != nil {
+		return [][]string{header, {procNetDev, "error: " + err.Error(), ""}}
+	}
+
+	pass := recv.record(xdpPass).total
+	if !c.started {
+		c.started, c.pass, c.stack = true, pass, stack
+	}
+
+	xdp := datarec{rxPackets: sinceZero(pass.rxPackets, c.pass.rxPackets), rxBytes: sinceZero(pass.rxBytes, c.pass.rxBytes)}
+	kernel := datarec{rxPackets: sinceZero(stack.rxPackets, c.stack.rxPackets), rxBytes: sinceZero(stack.rxBytes, c.stack.rxBytes)}
+
+	return [][]string{
+		header,
+		{"XDP_PASS", fmt.Sprintf("%d", xdp.rxPackets), fmt.Sprintf("%d", xdp.rxBytes)},
+		{procNetDev, fmt.Sprintf("%d", kernel.rxPackets), fmt.Sprintf("%d", kernel.rxBytes)},
+		{
+			"Difference",
+			fmt.Sprintf("%+d", int64(kernel.rxPackets-xdp.rxPackets)),
+			fmt.Sprintf("%+d", int64(kernel.rxBytes-xdp.rxBytes)),
+		},
+	}
+}
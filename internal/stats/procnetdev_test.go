@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadInterfaceCounters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	dev := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
+  eth0: 9000000    7000    0    0    0     0          0         0   500000     400    0    0    0     0       0          0
+`
+	if err := os.WriteFile(path, []byte(dev), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := readInterfaceCounters(path, "eth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.rxPackets != 7000 || rec.rxBytes != 9000000 {
+		t.Errorf("got %d packets, %d bytes, want 7000 packets, 9000000 bytes", rec.rxPackets, rec.rxBytes)
+	}
+
+	if _, err := readInterfaceCounters(path, "eth1"); err == nil {
+		t.Error("reading a missing interface succeeded")
+	}
+}
+
+func TestInterfaceRows(t *testing.T) {
+	start := StatsRecord{Records: make([]record, NumActions)}
+	start.Records[xdpPass].total = datarec{rxPackets: 100, rxBytes: 10000}
+	start.Records[1].total = datarec{rxPackets: 5000, rxBytes: 500000}
+
+	c := &interfaceCounts{iface: "eth0"}
+	c.rows(start, datarec{rxPackets: 1000, rxBytes: 200000}, nil)
+
+	// the drops grow too, but never reach the stack
+	recv := StatsRecord{Records: make([]record, NumActions)}
+	recv.Records[xdpPass].total = datarec{rxPackets: 150, rxBytes: 15000}
+	recv.Records[1].total = datarec{rxPackets: 9000, rxBytes: 900000}
+
+	got := c.rows(recv, datarec{rxPackets: 1052, rxBytes: 205200}, nil)
+	want := [][]string{
+		{"Interface eth0", "Packets", "Bytes"},
+		{"XDP_PASS", "50", "5000"},
+		{procNetDev, "52", "5200"},
+		{"Difference", "+2", "+200"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+
+	got = c.rows(recv, datarec{}, errors.New("interface eth0 not found"))
+	if len(got) != 2 || got[1][1] != "error: interface eth0 not found" {
+		t.Errorf("rows on a read error = %v, want the error", got)
+	}
+}
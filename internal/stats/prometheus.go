@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSeries is the number of series the prometheus sink created by
+// NewSink exports at most.
+const DefaultMaxSeries = 1000
+
+// prometheusSink serves the latest sample of every action in the Prometheus
+// text exposition format
+type prometheusSink struct {
+	srv *http.Server
+
+	// maxSeries caps the number of series exported, zero meaning no cap
+	maxSeries int
+
+	mu sync.Mutex
+	// latest holds the last sample of each label set
+	latest []Sample
+	// dropped holds the label sets left out for the cap, so each is only
+	// logged once
+	dropped map[string]bool
+}
+
+func newPrometheusSinkFactory(addr string) (OutputSink, error) {
+	return NewPrometheusSink(addr, DefaultMaxSeries)
+}
+
+// checkPrometheusAddr checks the address the metrics are served at
+func checkPrometheusAddr(addr string) error {
+	if addr == "" {
+		return fmt.Errorf("missing listen address, expected prometheus:host:port or prometheus:unix:/path")
+	}
+
+	return CheckListenAddr(addr)
+}
+
+// NewPrometheusSink returns a sink serving the latest samples as Prometheus
+// metrics at http://addr/metrics, or on the Unix socket of an addr of the
+// form unix:/path, until it is closed. Actions first seen once
+// maxSeries series are exported are left out; zero exports every action.
+func NewPrometheusSink(addr string, maxSeries int) (OutputSink, error) {
+	if err := checkPrometheusAddr(addr); err != nil {
+		return nil, err
+	}
+
+	l, err := Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &prometheusSink{maxSeries: maxSeries, dropped: make(map[string]bool)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", p.serveMetrics)
+
+	p.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go p.srv.Serve(l)
+
+	return p, nil
+}
+
+func (p *prometheusSink) Write(s Sample) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.latest {
+		if p.latest[i].Action == s.Action && p.latest[i].Label == s.Label && p.latest[i].Map == s.Map {
+			p.latest[i] = s
+			return nil
+		}
+	}
+
+	if p.maxSeries > 0 && (len(p.latest)+1)*len(prometheusMetrics) > p.maxSeries {
+		if key := labels(s); !p.dropped[key] {
+			p.dropped[key] = true
+			log.Printf("prometheus: not exporting {%s}, the cap of %d series is reached\n", key, p.maxSeries)
+		}
+
+		return nil
+	}
+
+	p.latest = append(p.latest, s)
+
+	// keep the series in a stable order whatever order the actions come in
+	sort.Slice(p.latest, func(i, j int) bool {
+		a, b := p.latest[i], p.latest[j]
+		if a.Map != b.Map {
+			return a.Map < b.Map
+		}
+
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+
+		return a.Action < b.Action
+	})
+
+	return nil
+}
+
+func (p *prometheusSink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	return p.srv.Shutdown(ctx)
+}
+
+var prometheusMetrics = []struct {
+	name, kind, help string
+	value            func(Sample) string
+}{
+	{"xdp_rx_packets_total", "counter", "Packets seen by the XDP program, by action.",
+		func(s Sample) string { return fmt.Sprint(s.Packets) }},
+	{"xdp_rx_bytes_total", "counter", "Bytes seen by the XDP program, by action.",
+		func(s Sample) string { return fmt.Sprint(s.Bytes) }},
+	{"xdp_rx_pps", "gauge", "Packets per second over the last period, by action.",
+		func(s Sample) string { return fmt.Sprint(s.PPS) }},
+	{"xdp_rx_bps", "gauge", "Bits per second over the last period, by action.",
+		func(s Sample) string { return fmt.Sprint(s.BPS) }},
+}
+
+func (p *prometheusSink) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	samples := append([]Sample(nil), p.latest...)
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	writeMetrics(w, samples)
+}
+
+// writeMetrics writes samples in the Prometheus text format
+func writeMetrics(w io.Writer, samples []Sample) {
+	for _, m := range prometheusMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+
+		for _, s := range samples {
+			fmt.Fprintf(w, "%s{%s} %s\n", m.name, labels(s), m.value(s))
+		}
+	}
+}
+
+// labels returns the label set of the series of s, its labels always in the
+// same order
+func labels(s Sample) string {
+	l := `action="` + escapeLabel(s.Action) + `"`
+	if s.Label != "" {
+		l += `,label="` + escapeLabel(s.Label) + `"`
+	}
+
+	if s.Map != "" {
+		l += `,map="` + escapeLabel(s.Map) + `"`
+	}
+
+	return l
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ranking orders the rows of the table by a sample field, highest first, and
+// keeps the top Limit of them, like top does for processes.
+type Ranking struct {
+	// Field is the JSON name of the field ranked by, as in filters
+	Field string
+
+	// Limit is the number of rows kept. Zero keeps every row.
+	Limit int
+}
+
+// ParseRanking returns the ranking by field keeping limit rows.
+func ParseRanking(field string, limit int) (*Ranking, error) {
+	field = strings.ToLower(strings.TrimSpace(field))
+
+	if _, ok := sampleFields[field]; !ok {
+		return nil, fmt.Errorf("invalid rank field %q (known fields: %s)", field, strings.Join(filterFields(), ", "))
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d", limit)
+	}
+
+	return &Ranking{Field: field, Limit: limit}, nil
+}
+
+// rank sorts rows by the ranked field. Ties are broken by name rather than by
+// the order the rows came in, so rows with equal values don't swap places
+// between ticks.
+func (r *Ranking) rank(rows []Sample) []Sample {
+	if r == nil {
+		return rows
+	}
+
+	value := sampleFields[r.Field]
+	ranked := append([]Sample(nil), rows...)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := value(ranked[i]), value(ranked[j])
+		if a != b {
+			return a > b
+		}
+
+		if ranked[i].Map != ranked[j].Map {
+			return ranked[i].Map < ranked[j].Map
+		}
+
+		return ranked[i].Action < ranked[j].Action
+	})
+
+	if r.Limit > 0 && len(ranked) > r.Limit {
+		ranked = ranked[:r.Limit]
+	}
+
+	return ranked
+}
@@ -0,0 +1,28 @@
+package stats
+
+import "time"
+
+// rateWindow holds the records read over the last rate window of a source,
+// oldest first, so rates can be computed across the whole window rather than
+// the last tick alone
+type rateWindow struct {
+	records []StatsRecord
+}
+
+// base returns the oldest record read since cutoff, dropping the records read
+// before it, or fallback if there is none.
+func (w *rateWindow) base(cutoff time.Time, fallback StatsRecord) StatsRecord {
+	for len(w.records) > 0 && w.records[0].record(0).timestamp.Before(cutoff) {
+		w.records = w.records[1:]
+	}
+
+	if len(w.records) == 0 {
+		return fallback
+	}
+
+	return w.records[0]
+}
+
+func (w *rateWindow) push(rec StatsRecord) {
+	w.records = append(w.records, rec)
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// ActionStat is the statistics of one action over the period since the
+// previous read, as returned by Reader.Sample.
+type ActionStat struct {
+	Action  string
+	Packets uint64
+	Bytes   uint64
+	PPS     float64
+	BPS     float64
+	Period  time.Duration
+}
+
+// Reader reads the counters of stats maps on demand, each read computing the
+// rates since the previous one. It is the collection path of every session,
+// and lets programs embed the collector without a terminal UI, through the
+// public github.com/bxffour/nstats/stats package.
+type Reader struct {
+	opts    *Options
+	sources []Source
+
+	// baseline is the first record of every source, prev the last one read
+	// successfully
+	baseline, prev []StatsRecord
+}
+
+// NewReader returns a Reader of statsMap, an *ebpf.Map or anything looking up
+// its values the same way. Nothing is read until Sample is called.
+func NewReader(statsMap MapLooker) *Reader {
+	return &Reader{opts: &Options{}, sources: []Source{{Map: statsMap}}}
+}
+
+// Sample reads the map and returns the stats of every action since the
+// previous call. The first call has the totals alone, its rates and periods
+// being zero.
+func (r *Reader) Sample() ([]ActionStat, error) {
+	if r.prev == nil {
+		if err := r.start(); err != nil {
+			return nil, err
+		}
+
+		return actionStats(calcStats(r.baseline[0], r.baseline[0])), nil
+	}
+
+	_, samples, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return actionStats(samples), nil
+}
+
+// newReader returns a reader of sources for a session configured by opts,
+// having read the baseline of every source
+func (o *Options) newReader(sources []Source) (*Reader, error) {
+	r := &Reader{opts: o, sources: sources}
+
+	if err := r.start(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// start reads the baseline the first rates are taken against
+func (r *Reader) start() error {
+	baseline, err := r.opts.collectAll(r.sources)
+	if err != nil {
+		return fmt.Errorf("error collecting stats: %w", err)
+	}
+
+	r.baseline, r.prev = baseline, baseline
+
+	return nil
+}
+
+// read samples every source against its previous successful read, which it
+// becomes unless reading fails
+func (r *Reader) read() ([]StatsRecord, []Sample, error) {
+	recv, samples, err := r.opts.sampleAll(r.sources, r.prev)
+	if err != nil {
+		return recv, nil, err
+	}
+
+	r.prev = recv
+
+	return recv, samples, nil
+}
+
+func actionStats(samples []Sample) []ActionStat {
+	stats := make([]ActionStat, 0, len(samples))
+
+	for _, s := range samples {
+		stats = append(stats, ActionStat{
+			Action:  s.Action,
+			Packets: s.Packets,
+			Bytes:   s.Bytes,
+			PPS:     s.PPS,
+			BPS:     s.BPS,
+			Period:  time.Duration(s.Period * float64(time.Second)),
+		})
+	}
+
+	return stats
+}
@@ -0,0 +1,703 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// RenderStats shows the stats read from statsMap in the terminal until 'q' is
+// pressed or ctx is cancelled.
+func RenderStats(ctx context.Context, statsMap MapLooker, opts Options) error {
+	return RenderMaps(ctx, []Source{{Map: statsMap}}, opts)
+}
+
+// RenderMaps is RenderStats showing the actions of every source in one table.
+// The sparklines of ExtendedStats and the interface counters only make sense
+// for a single map and are left out when there are several.
+func RenderMaps(ctx context.Context, sources []Source, opts Options) error {
+	return renderMaps(ctx, sources, opts, termScreen{})
+}
+
+// screen is the terminal the table is drawn on and the keys are read from,
+// termbox's unless a test gives one of its own
+type screen interface {
+	Init() error
+	Close()
+	Render(items ...ui.Drawable)
+	Clear()
+	Size() (width, height int)
+	Events() <-chan ui.Event
+}
+
+// termScreen is the terminal, through termui
+type termScreen struct{}
+
+func (termScreen) Init() error                 { return ui.Init() }
+func (termScreen) Close()                      { ui.Close() }
+func (termScreen) Render(items ...ui.Drawable) { ui.Render(items...) }
+func (termScreen) Clear()                      { ui.Clear() }
+func (termScreen) Size() (int, int)            { return ui.TerminalDimensions() }
+func (termScreen) Events() <-chan ui.Event     { return ui.PollEvents() }
+
+// renderMaps is RenderMaps drawing on scr and reading its events until 'q'
+// is pressed or ctx is cancelled
+func renderMaps(ctx context.Context, sources []Source, opts Options, scr screen) (err error) {
+	if len(sources) > 1 {
+		opts.ExtendedStats = false
+		opts.Interface = ""
+	}
+
+	reader, err := opts.newReader(sources)
+	if err != nil {
+		return err
+	}
+
+	baseline := reader.baseline
+
+	if opts.ZeroOnStart {
+		opts.zero = baseline
+	}
+
+	// drained once the terminal is restored, so drops can be logged
+	defer opts.bufferSinks()()
+
+	if err := scr.Init(); err != nil {
+		return err
+	}
+
+	opts.screen = scr
+
+	opts.start = opts.clock().Now()
+
+	// idle is set when the session ends for ExitAfterIdle
+	var idle bool
+
+	// a panic would otherwise leave the terminal in raw mode. Close must
+	// only run once, so it is called here rather than deferred on its own
+	defer func() {
+		scr.Close()
+
+		for _, v := range opts.violations {
+			log.Printf("verify-counters: %s\n", v)
+		}
+
+		for _, e := range opts.alerts {
+			log.Println(e)
+		}
+
+		if r := recover(); r != nil {
+			log.Printf("panic in the render loop: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("render loop panicked: %v", r)
+		}
+
+		if err != nil {
+			return
+		}
+
+		if idle {
+			fmt.Printf("No traffic for %s, exiting\n", opts.ExitAfterIdle)
+		}
+
+		if end, err := opts.collectAll(sources); err == nil {
+			fmt.Println(sessionSummary(baseline, end, opts.clock().Now().Sub(opts.start)))
+		}
+
+		for _, slo := range opts.SLOs {
+			fmt.Printf("SLO %s\n", slo)
+		}
+	}()
+
+	if opts.PeakHold {
+		opts.peaks = newPeakTracker()
+	}
+
+	if opts.ShowAverage {
+		opts.averages = newAverageTracker()
+	}
+
+	if opts.FreezeColumns || opts.ColumnWidth > 0 {
+		opts.widths = &columnWidths{least: opts.ColumnWidth}
+	}
+
+	if opts.ShowIdle {
+		opts.idle = newIdleTracker()
+	}
+
+	opts.tui = true
+
+	table := newStatsTable(&opts)
+	banner := newDropBanner(&opts)
+
+	drawables := []ui.Drawable{banner}
+
+	graph := newGraph(&opts)
+
+	if opts.Graph {
+		drawables = append(drawables, graph)
+	}
+
+	drawables = append(drawables, table)
+
+	header := newCompactHeader(&opts)
+
+	if opts.CompactHeader != "" {
+		header.update(&opts, opts.clock().Now())
+		drawables = append([]ui.Drawable{header}, drawables...)
+	}
+
+	history := opts.History
+	if history == nil {
+		history = NewHistory(opts.historySize())
+	}
+
+	var (
+		dropLine   = widgets.NewSparkline()
+		bpsLine    = widgets.NewSparkline()
+		sparklines = widgets.NewSparklineGroup(dropLine, bpsLine)
+	)
+
+	if opts.ExtendedStats {
+		dropLine.Title = "Drop %"
+		dropLine.MaxVal = 100
+		dropLine.LineColor = opts.color(ui.ColorRed)
+		bpsLine.Title = "Bits Per Sec"
+		bpsLine.LineColor = opts.color(ui.ColorGreen)
+		sparklines.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+
+		drawables = append(drawables, sparklines)
+	}
+
+	efficiency := newEfficiencyGauge(&opts)
+
+	if opts.Efficiency {
+		drawables = append(drawables, efficiency)
+	}
+
+	ifaceTable := widgets.NewTable()
+
+	var iface *interfaceCounts
+
+	if opts.Interface != "" {
+		iface = &interfaceCounts{iface: opts.Interface}
+
+		counters, readErr := readInterfaceCounters(procNetDev, opts.Interface)
+		ifaceTable.Rows = iface.rows(baseline[0], counters, readErr)
+		ifaceTable.TextStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+		ifaceTable.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+		ifaceTable.TextAlignment = termui.AlignCenter
+
+		drawables = append(drawables, ifaceTable)
+	}
+
+	derivedTable := widgets.NewTable()
+
+	if len(opts.Derived) > 0 {
+		derivedTable.Rows = opts.derivedRows(nil)
+		derivedTable.Title = " Derived "
+		derivedTable.TextStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+		derivedTable.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+		derivedTable.TextAlignment = termui.AlignCenter
+
+		drawables = append(drawables, derivedTable)
+	}
+
+	perCPUTable := widgets.NewTable()
+
+	if opts.PerCPU {
+		perCPUTable.Rows = opts.perCPURows(baseline[0], baseline[0])
+		perCPUTable.Title = " Per CPU "
+		perCPUTable.TextStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+		perCPUTable.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+		perCPUTable.TextAlignment = termui.AlignCenter
+
+		drawables = append(drawables, perCPUTable)
+	}
+
+	if len(opts.Metadata) > 0 {
+		metadata := widgets.NewParagraph()
+		metadata.Title = " Maps "
+		metadata.Text = strings.Join(opts.Metadata, "\n")
+		metadata.TextStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+		metadata.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+
+		drawables = append(drawables, metadata)
+	}
+
+	// drawn last, over the others
+	picker := newColumnPicker(table, &opts)
+	drawables = append(drawables, picker)
+
+	uiEvents := scr.Events()
+
+	var lastPaint time.Time
+
+	timer := time.NewTimer(opts.tickInterval())
+	defer timer.Stop()
+
+	// lastGood is when the map was last read successfully
+	lastGood := opts.clock().Now()
+
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(opts.tickInterval())
+
+			// each tick reads the maps once, its rates taken against the
+			// previous successful read
+			prev := reader.prev
+
+			recv, samples, err := reader.read()
+			if errors.Is(err, errUnstableRead) {
+				break
+			}
+
+			if err != nil && opts.StaleAfter > 0 {
+				// keep showing the last samples, marked stale once they
+				// are old enough
+				warn(warnStaleRead, 1)
+				opts.markStale(table, opts.clock().Now().Sub(lastGood), err)
+				scr.Render(drawables...)
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if opts.idleTooLong(samples, opts.clock().Now()) {
+				idle = true
+				return nil
+			}
+
+			if opts.Efficiency {
+				efficiency.update(baseline, prev, recv)
+			}
+
+			lastGood = opts.clock().Now()
+
+			if opts.CompactHeader != "" {
+				header.update(&opts, lastGood)
+			}
+			opts.markStale(table, 0, nil)
+			banner.update(&opts, baseline, recv, samples)
+
+			opts.warming = opts.clock().Now().Sub(opts.start) < opts.Warmup
+
+			if !opts.warming {
+				if last := history.Last(1); len(last) == 1 {
+					opts.last = &last[0]
+				}
+
+				history.Append(newSnapshot(samples))
+
+				if _, err := opts.writeSinks(samples); err != nil {
+					return err
+				}
+			}
+
+			if !opts.warming {
+				opts.trackSLOs(samples)
+
+				if err := opts.raiseAlerts(samples); err != nil {
+					return err
+				}
+			}
+
+			if opts.ExtendedStats && !opts.warming {
+				dropLine.Data = dropLine.Data[:0]
+				bpsLine.Data = bpsLine.Data[:0]
+
+				for _, snap := range history.Last(DefaultHistorySize) {
+					dropLine.Data = append(dropLine.Data, dropPercent(opts.actions(snap.Samples)))
+					bpsLine.Data = append(bpsLine.Data, totalBPS(opts.actions(snap.Samples)))
+				}
+
+				dropLine.Title = fmt.Sprintf("Drop %% (%.1f%%)", dropLine.Data[len(dropLine.Data)-1])
+
+				bps := bpsLine.Data[len(bpsLine.Data)-1]
+				bpsLine.Title = fmt.Sprintf("Bits Per Sec (%s)", strings.TrimSpace(formatRate(bps, "bit")))
+
+				// idle traffic is drawn flat, not scaled by a zero peak
+				bpsLine.MaxVal = 0
+				if highest(bpsLine.Data) == 0 {
+					bpsLine.MaxVal = 1
+				}
+			}
+
+			if opts.Graph && !opts.warming {
+				graph.update(&opts, history.Last(opts.graphHistory()))
+			}
+
+			// the interface going away leaves the table to say so
+			if iface != nil {
+				counters, err := readInterfaceCounters(procNetDev, opts.Interface)
+				ifaceTable.Rows = iface.rows(recv[0], counters, err)
+			}
+
+			if len(opts.Derived) > 0 {
+				derivedTable.Rows = opts.derivedRows(samples)
+			}
+
+			if opts.PerCPU {
+				perCPUTable.Rows = opts.perCPURows(prev[0], recv[0])
+			}
+
+			rows := opts.Ranking.rank(opts.order(samples))
+
+			if opts.idle != nil {
+				opts.idle.update(rows, opts.clock().Now())
+			}
+
+			if opts.peaks != nil && !opts.warming {
+				opts.peaks.update(rows, opts.clock().Now())
+			}
+
+			if opts.averages != nil && !opts.warming {
+				opts.averages.update(rows)
+			}
+
+			if opts.HideZero {
+				rows = withTraffic(rows)
+			}
+
+			stats := opts.formatStats(rows)
+			failed := opts.failedRows(sources)
+
+			table.styles = make(map[int]ui.Style)
+			for i, s := range rows {
+				if opts.smallPackets(s) || opts.alerting(s) {
+					table.styles[i+1] = opts.alertStyle()
+					stats[i].Action = opts.alert(stats[i].Action)
+				}
+			}
+
+			for i, f := range failed {
+				table.styles[len(rows)+i+1] = opts.alertStyle()
+				f.Action = opts.alert(f.Action)
+			}
+
+			table = updateTable(append(stats, failed...), table, &opts)
+
+			// the samples above still feed the history and sinks when the
+			// repaint is skipped
+			if opts.RenderFPS > 0 && opts.clock().Now().Sub(lastPaint) < time.Second/time.Duration(opts.RenderFPS) {
+				break
+			}
+
+			lastPaint = opts.clock().Now()
+
+			opts.layout(drawables)
+			scr.Render(drawables...)
+
+		case <-ctx.Done():
+			return nil
+
+		case e := <-uiEvents:
+			if picker.open && e.Type == ui.KeyboardEvent && e.ID != "<C-c>" {
+				picker.handle(e.ID)
+				opts.layout(drawables)
+				scr.Clear()
+				scr.Render(drawables...)
+
+				break
+			}
+
+			switch e.ID {
+			case "q", "<C-c>":
+				return nil
+			case "d":
+				banner.dismissed = true
+				opts.layout(drawables)
+				scr.Clear()
+				scr.Render(drawables...)
+			case "c":
+				picker.toggle()
+				opts.layout(drawables)
+				scr.Render(drawables...)
+			case "<Resize>":
+				opts.layout(drawables)
+				scr.Clear()
+				scr.Render(drawables...)
+			case "<Up>", "<Down>", "<Left>", "<Right>":
+				switch e.ID {
+				case "<Up>":
+					table.scroll(-1, 0)
+				case "<Down>":
+					table.scroll(1, 0)
+				case "<Left>":
+					table.scroll(0, -1)
+				case "<Right>":
+					table.scroll(0, 1)
+				}
+
+				scr.Render(drawables...)
+			case "s":
+				last := history.Last(1)
+				if len(last) == 0 {
+					break
+				}
+
+				dir := opts.SnapshotDir
+				if dir == "" {
+					dir = "."
+				}
+
+				var msg string
+
+				name, err := writeSnapshot(dir, last[0])
+				if err != nil {
+					msg = fmt.Sprintf("snapshot failed: %v", err)
+				} else {
+					msg = fmt.Sprintf("snapshot written to %s", name)
+				}
+
+				if opts.Label != "" {
+					msg = opts.Label + " - " + msg
+				}
+
+				table.Title = fmt.Sprintf(" %s ", msg)
+
+				scr.Render(drawables...)
+			}
+		}
+	}
+}
+
+// markStale dims the table and shows the age of its samples in the title
+// once they are older than StaleAfter, and restores it otherwise
+func (o *Options) markStale(table *scrollTable, age time.Duration, err error) {
+	if age <= o.StaleAfter {
+		table.TextStyle = ui.NewStyle(o.color(ui.ColorWhite))
+		if table.stale {
+			table.Title = labelTitle(o.Label)
+			table.stale = false
+		}
+
+		return
+	}
+
+	msg := fmt.Sprintf("stale: last sample %s ago (%v)", age.Round(time.Second), err)
+	if o.Label != "" {
+		msg = o.Label + " - " + msg
+	}
+
+	table.stale = true
+	table.TextStyle = ui.NewStyle(o.color(staleColor))
+
+	if o.A11y {
+		// grey is hard to tell apart, the title says it instead
+		msg = "* " + msg
+		table.TextStyle = ui.NewStyle(ui.ColorClear, ui.ColorClear, ui.ModifierUnderline)
+	}
+	table.Title = fmt.Sprintf(" %s ", msg)
+}
+
+// staleColor is the dimmed grey of stale tables
+const staleColor = ui.Color(8)
+
+// labelTitle returns the table title showing label
+func labelTitle(label string) string {
+	if label == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" %s ", label)
+}
+
+// NewTable returns a table widget showing samples, as drawn by RenderStats,
+// placed in rect. It neither initialises termui nor handles events, so it can
+// be embedded in another termui application and rebuilt on every tick. The
+// termui table draws a line per row, so CellStacked is ignored.
+func NewTable(rect image.Rectangle, samples []Sample, opts Options) *widgets.Table {
+	opts.CellStacked = false
+
+	table := updateTable(opts.formatStats(samples), newStatsTable(&opts), &opts)
+
+	table.Table.Rows = table.rows
+	table.SetRect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y)
+
+	return table.Table
+}
+
+// newStatsTable returns the styled stats table holding only its header
+func newStatsTable(opts *Options) *scrollTable {
+	table := newScrollTable()
+	table.rows = [][]string{opts.header()}
+
+	table.TextStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+	table.BorderStyle = ui.NewStyle(opts.color(ui.ColorCyan))
+	table.TitleStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+
+	if opts.A11y {
+		table.BorderStyle = ui.NewStyle(opts.color(ui.ColorWhite))
+		table.TitleStyle = ui.NewStyle(opts.color(ui.ColorWhite), ui.ColorClear, ui.ModifierBold)
+	}
+	table.RowSeparator = !opts.NoRowSeparator
+	table.FillRow = !opts.NoFillRow
+	table.TextAlignment = termui.AlignCenter
+
+	if opts.Baseline != nil {
+		table.rows[0] = append(table.rows[0], "vs Baseline")
+	}
+
+	if opts.ShowIdle {
+		table.rows[0] = append(table.rows[0], "Idle")
+	}
+
+	if opts.PeakHold {
+		table.rows[0] = append(table.rows[0], "Peak")
+	}
+
+	if opts.ShowAverage {
+		table.rows[0] = append(table.rows[0], "Average")
+	}
+
+	if len(opts.SLOs) > 0 {
+		table.rows[0] = append(table.rows[0], "SLO")
+	}
+
+	table.rows[0] = append(table.rows[0], opts.Counters...)
+
+	table.Title = labelTitle(opts.Label)
+
+	return table
+}
+
+func updateTable(stats []*stats, table *scrollTable, opts *Options) *scrollTable {
+	table.rows = table.rows[:1]
+
+	if table.styles == nil {
+		table.styles = make(map[int]ui.Style)
+	}
+
+	for i, s := range stats {
+		// the rows styled already need attention, which wins over the color
+		// of their action
+		if _, styled := table.styles[i+1]; !styled {
+			if style, ok := opts.severityStyle(s); ok {
+				table.styles[i+1] = style
+			}
+		}
+
+		row := opts.row(s.Action, s.Packets, s.PPs, s.Bytes, s.BPs, s.Period)
+		if s.Baseline != "" {
+			row = append(row, s.Baseline)
+		}
+
+		if opts.ShowIdle {
+			row = append(row, s.Idle)
+		}
+
+		if opts.PeakHold {
+			row = append(row, s.Peak)
+		}
+
+		if opts.ShowAverage {
+			row = append(row, s.Average)
+		}
+
+		if len(opts.SLOs) > 0 {
+			row = append(row, s.SLO)
+		}
+
+		row = append(row, s.Counters...)
+
+		if opts.widths != nil {
+			row = opts.widths.freeze(row)
+		}
+
+		table.rows = append(table.rows, row)
+	}
+
+	return table
+}
+
+// layout stacks the widgets from the top of the terminal, sized to its width
+// capped by MaxWidth. The stats table gives up height, and scrolls, when the
+// widgets don't all fit.
+func (o *Options) layout(drawables []ui.Drawable) {
+	width, height := o.screen.Size()
+	if o.MaxWidth > 0 && width > o.MaxWidth {
+		width = o.MaxWidth
+	}
+
+	others := 0
+	for _, d := range drawables {
+		switch d.(type) {
+		case *scrollTable, *columnPicker:
+		default:
+			others += widgetHeight(d)
+		}
+	}
+
+	y := 0
+
+	for _, d := range drawables {
+		if p, ok := d.(*columnPicker); ok {
+			p.place(width, height)
+			continue
+		}
+
+		h := widgetHeight(d)
+
+		if w, ok := d.(*scrollTable); ok && h > height-others {
+			// room for the header and at least one row
+			h = height - others
+			if least := tableHeight(2, w.lines(), w.RowSeparator); h < least {
+				h = least
+			}
+		}
+
+		d.SetRect(0, y, width, y+h)
+		y += h
+	}
+}
+
+func widgetHeight(d ui.Drawable) int {
+	switch w := d.(type) {
+	case *dropBanner:
+		if !w.visible() {
+			return 0
+		}
+
+		return 3
+	case *scrollTable:
+		return tableHeight(len(w.rows), w.lines(), w.RowSeparator)
+	case *widgets.Table:
+		return tableHeight(len(w.Rows), 1, w.RowSeparator)
+	case *efficiencyGauge:
+		return 3
+	case *graph:
+		return w.height()
+	case *widgets.SparklineGroup:
+		// a title row and two rows of bars per sparkline, as in the graph
+		return 3*len(w.Sparklines) + 2
+	case *compactHeader:
+		return 1
+	case *widgets.Paragraph:
+		return strings.Count(w.Text, "\n") + 3
+	default:
+		return 6
+	}
+}
+
+// tableHeight is the height of a table with n rows of lines lines, including
+// its borders
+func tableHeight(n, lines int, separators bool) int {
+	if separators {
+		// every row but the last is followed by a separator
+		return n*(lines+1) + 1
+	}
+
+	return n*lines + 2
+}
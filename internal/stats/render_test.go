@@ -0,0 +1,129 @@
+package stats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+
+	"github.com/bxffour/nstats/internal/stats"
+	"github.com/bxffour/nstats/internal/stats/statstest"
+)
+
+// tickSink counts the ticks written to it, calling tick after each
+type tickSink struct {
+	ticks int
+	tick  func(n int)
+}
+
+func (s *tickSink) Write(sample stats.Sample) error {
+	if sample.Action == stats.Actions()[0].Name {
+		s.ticks++
+		s.tick(s.ticks)
+	}
+
+	return nil
+}
+
+func (s *tickSink) Close() error { return nil }
+
+// render runs the render loop over m until it returns or the test times
+// out, failing the test if it returns an error
+func render(t *testing.T, ctx context.Context, m *statstest.Map, opts stats.Options, events <-chan ui.Event) (renders int) {
+	t.Helper()
+
+	type result struct {
+		renders int
+		closed  bool
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		renders, closed, err := stats.RenderWithEvents(ctx, m, opts, events)
+		done <- result{renders, closed, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+
+		if !r.closed {
+			t.Error("the screen was left open")
+		}
+
+		return r.renders
+	case <-time.After(10 * time.Second):
+		t.Fatal("the render loop didn't return")
+		return 0
+	}
+}
+
+func TestRenderQuitsOnQ(t *testing.T) {
+	events := make(chan ui.Event, 1)
+	sink := &tickSink{tick: func(n int) {
+		if n == 3 {
+			events <- ui.Event{Type: ui.KeyboardEvent, ID: "q"}
+		}
+	}}
+
+	opts := stats.Options{Interval: time.Millisecond, Sinks: []stats.OutputSink{sink}}
+
+	if renders := render(t, context.Background(), statstest.NewMap(stats.NumActions, 2), opts, events); renders < 3 {
+		t.Errorf("the table was drawn %d times over %d ticks", renders, sink.ticks)
+	}
+}
+
+func TestRenderReturnsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sink := &tickSink{tick: func(n int) {
+		if n == 3 {
+			cancel()
+		}
+	}}
+
+	// with every widget up
+	opts := stats.Options{Interval: time.Millisecond, Sinks: []stats.OutputSink{sink}, ExtendedStats: true, Graph: true, Efficiency: true}
+
+	render(t, ctx, statstest.NewMap(stats.NumActions, 2), opts, make(chan ui.Event))
+
+	if sink.ticks < 3 {
+		t.Errorf("returned after %d ticks, before being cancelled", sink.ticks)
+	}
+}
+
+func TestRenderReadsOncePerTick(t *testing.T) {
+	m := statstest.NewMap(stats.NumActions, 2)
+
+	// the lookups made by the time each tick is written
+	var lookups []int
+
+	events := make(chan ui.Event, 1)
+	sink := &tickSink{tick: func(n int) {
+		lookups = append(lookups, m.Lookups())
+		if n == 5 {
+			events <- ui.Event{Type: ui.KeyboardEvent, ID: "q"}
+		}
+	}}
+
+	opts := stats.Options{Interval: time.Millisecond, Sinks: []stats.OutputSink{sink}}
+
+	render(t, context.Background(), m, opts, events)
+
+	// the baseline and one read per tick
+	for i, n := range lookups {
+		if want := (i + 2) * stats.NumActions; n != want {
+			t.Errorf("tick %d: %d lookups, want %d", i+1, n, want)
+		}
+	}
+
+	// the session summary reads the map once more
+	if want := (len(lookups) + 2) * stats.NumActions; m.Lookups() != want {
+		t.Errorf("%d lookups over %d ticks, want %d", m.Lookups(), len(lookups), want)
+	}
+}
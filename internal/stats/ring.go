@@ -0,0 +1,176 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// The ring file starts with a header followed by slots of ringSlotSize
+// bytes, all little endian:
+//
+//	header: magic [8]byte, version uint32, slots uint32, written uint64
+//	slot:   timestamp int64 (ns), packets uint64, pps float64, bytes uint64,
+//	        bps float64, period float64, action [32]byte, label [32]byte,
+//	        map [64]byte
+//
+// written counts the samples ever written, the next going to slot
+// written % slots. Strings are NUL padded and truncated to their field.
+const (
+	ringMagic      = "NSTATRNG"
+	ringVersion    = 1
+	ringHeaderSize = 24
+	ringSlotSize   = 48 + 32 + 32 + 64
+)
+
+// ringSink keeps the last samples in a fixed size file, overwriting the
+// oldest, so recent history survives a crash
+type ringSink struct {
+	f       *os.File
+	slots   uint32
+	written uint64
+}
+
+// NewRingSink returns a sink keeping the last slots samples in the ring file
+// at path. A ring file of the same size is carried on, one of another size
+// or any other file is started afresh. ReadRing reads it back.
+func NewRingSink(path string, slots int) (OutputSink, error) {
+	if slots <= 0 {
+		return nil, fmt.Errorf("invalid ring size %d", slots)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ringSink{f: f, slots: uint32(slots)}
+
+	if slotCount, written, err := readRingHeader(f); err == nil && slotCount == r.slots {
+		r.written = written
+		return r, nil
+	}
+
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := r.writeHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *ringSink) writeHeader() error {
+	header := make([]byte, ringHeaderSize)
+	copy(header, ringMagic)
+	binary.LittleEndian.PutUint32(header[8:], ringVersion)
+	binary.LittleEndian.PutUint32(header[12:], r.slots)
+	binary.LittleEndian.PutUint64(header[16:], r.written)
+
+	_, err := r.f.WriteAt(header, 0)
+
+	return err
+}
+
+func (r *ringSink) Write(s Sample) error {
+	slot := make([]byte, ringSlotSize)
+
+	binary.LittleEndian.PutUint64(slot[0:], uint64(s.Timestamp.UnixNano()))
+	binary.LittleEndian.PutUint64(slot[8:], s.Packets)
+	binary.LittleEndian.PutUint64(slot[16:], math.Float64bits(s.PPS))
+	binary.LittleEndian.PutUint64(slot[24:], s.Bytes)
+	binary.LittleEndian.PutUint64(slot[32:], math.Float64bits(s.BPS))
+	binary.LittleEndian.PutUint64(slot[40:], math.Float64bits(s.Period))
+	copy(slot[48:80], s.Action)
+	copy(slot[80:112], s.Label)
+	copy(slot[112:176], s.Map)
+
+	off := ringHeaderSize + int64(r.written%uint64(r.slots))*ringSlotSize
+	if _, err := r.f.WriteAt(slot, off); err != nil {
+		return err
+	}
+
+	// the header is only moved on once the slot is written
+	r.written++
+
+	return r.writeHeader()
+}
+
+func (r *ringSink) Close() error { return r.f.Close() }
+
+func readRingHeader(f *os.File) (slots uint32, written uint64, err error) {
+	header := make([]byte, ringHeaderSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return 0, 0, err
+	}
+
+	if string(header[:8]) != ringMagic {
+		return 0, 0, errors.New("not a ring file")
+	}
+
+	if v := binary.LittleEndian.Uint32(header[8:]); v != ringVersion {
+		return 0, 0, fmt.Errorf("unsupported ring file version %d", v)
+	}
+
+	return binary.LittleEndian.Uint32(header[12:]), binary.LittleEndian.Uint64(header[16:]), nil
+}
+
+// ReadRing returns the samples kept in the ring file at path, oldest first.
+func ReadRing(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	slots, written, err := readRingHeader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	n := written
+	if n > uint64(slots) {
+		n = uint64(slots)
+	}
+
+	samples := make([]Sample, 0, n)
+	slot := make([]byte, ringSlotSize)
+
+	for i := written - n; i < written; i++ {
+		off := ringHeaderSize + int64(i%uint64(slots))*ringSlotSize
+		if _, err := f.ReadAt(slot, off); err != nil {
+			return nil, fmt.Errorf("%s: slot %d: %w", path, i%uint64(slots), err)
+		}
+
+		samples = append(samples, Sample{
+			SchemaVersion: SchemaVersion,
+			Timestamp:     time.Unix(0, int64(binary.LittleEndian.Uint64(slot[0:]))),
+			Packets:       binary.LittleEndian.Uint64(slot[8:]),
+			PPS:           math.Float64frombits(binary.LittleEndian.Uint64(slot[16:])),
+			Bytes:         binary.LittleEndian.Uint64(slot[24:]),
+			BPS:           math.Float64frombits(binary.LittleEndian.Uint64(slot[32:])),
+			Period:        math.Float64frombits(binary.LittleEndian.Uint64(slot[40:])),
+			Action:        ringString(slot[48:80]),
+			Label:         ringString(slot[80:112]),
+			Map:           ringString(slot[112:176]),
+		})
+	}
+
+	return samples, nil
+}
+
+func ringString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// SchemaVersion identifies the layout of Sample. It is bumped whenever a field
+// is removed, renamed or changes meaning, so consumers can detect changes.
+const SchemaVersion = 1
+
+// Sample is the statistics of a single XDP action over one sampling period. It
+// is the record emitted by every structured output, and the JSON Schema
+// returned by Schema is derived from it. The outputs write its fields in the
+// order they are declared, returned by SampleFields, so new fields go at the
+// end.
+type Sample struct {
+	SchemaVersion int       `json:"schema_version" desc:"version of the sample layout"`
+	Timestamp     time.Time `json:"timestamp" desc:"time the counters were read"`
+	Action        string    `json:"action" desc:"XDP action the counters belong to"`
+	Packets       uint64    `json:"packets" desc:"total packets seen for the action"`
+	PPS           float64   `json:"pps" desc:"packets per second over the period"`
+	Bytes         uint64    `json:"bytes" desc:"total bytes seen for the action"`
+	BPS           float64   `json:"bps" desc:"bits per second over the period"`
+	Period        float64   `json:"period" desc:"length of the sampling period in seconds"`
+	Label         string    `json:"label,omitempty" desc:"free-form label given with --label"`
+	Elapsed       float64   `json:"elapsed,omitempty" desc:"seconds since the session started, with --timestamps-relative"`
+	Map           string    `json:"map,omitempty" desc:"pin of the map the counters were read from, with --scan"`
+
+	Counters map[string]uint64 `json:"counters,omitempty" desc:"extra counters of the map's value schema by name, e.g. drop reasons, with --value-schema"`
+}
+
+// Schema returns the JSON Schema describing a Sample.
+func Schema() ([]byte, error) {
+	var (
+		properties = make(map[string]interface{}, len(fields))
+		required   = make([]string, 0, len(fields))
+	)
+
+	for _, f := range fields {
+		prop := map[string]interface{}{
+			"description": f.desc,
+		}
+
+		switch {
+		case f.typ == reflect.TypeOf(time.Time{}):
+			prop["type"] = "string"
+			prop["format"] = "date-time"
+		case f.typ.Kind() == reflect.String:
+			prop["type"] = "string"
+		case f.typ.Kind() == reflect.Float64:
+			prop["type"] = "number"
+		case f.typ.Kind() >= reflect.Uint && f.typ.Kind() <= reflect.Uint64:
+			prop["type"] = "integer"
+			prop["minimum"] = 0
+		case f.typ.Kind() == reflect.Map:
+			prop["type"] = "object"
+			prop["additionalProperties"] = map[string]interface{}{"type": "integer", "minimum": 0}
+		default:
+			prop["type"] = "integer"
+		}
+
+		properties[f.name] = prop
+
+		if !f.omitEmpty {
+			required = append(required, f.name)
+		}
+	}
+
+	properties["schema_version"].(map[string]interface{})["const"] = SchemaVersion
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      "nstats sample",
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}, "", "  ")
+}
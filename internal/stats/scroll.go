@@ -0,0 +1,204 @@
+package stats
+
+import (
+	"image"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// minColumnWidth is the narrowest a column gets before the table scrolls
+// horizontally instead of squeezing it further.
+const minColumnWidth = 16
+
+// scrollTable is a table that shows the window of its rows and columns that
+// fits its rectangle. The header row and the action column stay in place
+// while the rest scrolls.
+type scrollTable struct {
+	*widgets.Table
+
+	// rows holds every row, the first being the header
+	rows [][]string
+
+	// row and col are the first data row and column shown after the frozen
+	// ones
+	row, col int
+
+	// styles holds the styles of rows, by index in rows
+	styles map[int]ui.Style
+
+	// stale is set while the title shows the samples are stale
+	stale bool
+
+	// hidden holds the columns left out, by header
+	hidden map[string]bool
+}
+
+func newScrollTable() *scrollTable {
+	return &scrollTable{Table: widgets.NewTable()}
+}
+
+// scroll moves the window by rows and cols
+func (t *scrollTable) scroll(rows, cols int) {
+	t.row += rows
+	t.col += cols
+}
+
+func (t *scrollTable) Draw(buf *ui.Buffer) {
+	if len(t.rows) == 0 {
+		return
+	}
+
+	rows := t.shown()
+
+	lines := t.lines()
+
+	visibleRows := t.Inner.Dy() / lines
+	if t.RowSeparator {
+		// n rows are drawn on n*(lines+1)-1 lines
+		visibleRows = (t.Inner.Dy() + 1) / (lines + 1)
+	}
+
+	visibleCols := t.Inner.Dx() / minColumnWidth
+
+	t.row = clamp(t.row, 0, len(rows)-visibleRows)
+	t.col = clamp(t.col, 0, len(rows[0])-visibleCols)
+
+	window := make([][]string, 0, visibleRows)
+	t.Table.RowStyles = make(map[int]ui.Style)
+
+	for i, r := range rows {
+		if i != 0 && (i <= t.row || len(window) >= visibleRows) {
+			continue
+		}
+
+		cols := r[1:]
+		if len(cols) > visibleCols-1 && visibleCols > 1 {
+			cols = cols[t.col : t.col+visibleCols-1]
+		}
+
+		if style, ok := t.styles[i]; ok {
+			t.Table.RowStyles[len(window)] = style
+		}
+
+		window = append(window, append([]string{r[0]}, cols...))
+	}
+
+	if lines == 1 {
+		t.Table.Rows = window
+		t.Table.Draw(buf)
+
+		return
+	}
+
+	t.drawLines(buf, window, lines)
+}
+
+// shown returns the rows without the hidden columns. The action column is
+// always shown.
+func (t *scrollTable) shown() [][]string {
+	if len(t.hidden) == 0 {
+		return t.rows
+	}
+
+	keep := []int{0}
+	for j, header := range t.rows[0] {
+		if j > 0 && !t.hidden[header] {
+			keep = append(keep, j)
+		}
+	}
+
+	rows := make([][]string, 0, len(t.rows))
+	for _, r := range t.rows {
+		row := make([]string, 0, len(keep))
+		for _, j := range keep {
+			if j < len(r) {
+				row = append(row, r[j])
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// lines is the number of lines of the rows, that of their tallest cell
+func (t *scrollTable) lines() int {
+	lines := 1
+
+	for _, r := range t.rows {
+		for _, cell := range r {
+			if n := strings.Count(cell, "\n") + 1; n > lines {
+				lines = n
+			}
+		}
+	}
+
+	return lines
+}
+
+// drawLines draws rows of multi-line cells, every row taking lines lines.
+// termui draws one line per row, so each row is split into a row per line,
+// with blank rows drawn over by separators in between.
+func (t *scrollTable) drawLines(buf *ui.Buffer, window [][]string, lines int) {
+	styles := t.Table.RowStyles
+	t.Table.RowStyles = make(map[int]ui.Style)
+
+	var (
+		split      [][]string
+		separators []int
+	)
+
+	for i, r := range window {
+		if i > 0 && t.RowSeparator {
+			separators = append(separators, len(split))
+			split = append(split, make([]string, len(r)))
+		}
+
+		for l := 0; l < lines; l++ {
+			line := make([]string, len(r))
+			for j, cell := range r {
+				if cells := strings.Split(cell, "\n"); l < len(cells) {
+					line[j] = cells[l]
+				}
+			}
+
+			if style, ok := styles[i]; ok {
+				t.Table.RowStyles[len(split)] = style
+			}
+
+			split = append(split, line)
+		}
+	}
+
+	separator := t.RowSeparator
+	t.Table.RowSeparator = false
+	t.Table.Rows = split
+	t.Table.Draw(buf)
+	t.Table.RowSeparator = separator
+
+	horizontal := ui.NewCell(ui.HORIZONTAL_LINE, t.BorderStyle)
+	for _, i := range separators {
+		y := t.Inner.Min.Y + i
+		if y >= t.Inner.Max.Y {
+			break
+		}
+
+		buf.Fill(horizontal, image.Rect(t.Inner.Min.X, y, t.Inner.Max.X, y+1))
+	}
+}
+
+// clamp limits v to [lo, hi], preferring lo if hi < lo
+func clamp(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+
+	if v < lo {
+		v = lo
+	}
+
+	return v
+}
@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// sessionSummary describes the traffic counted by every source between the
+// records in start and those in end, e.g.
+// "Session: 12 GiB across 45,000,000 packets over 5m12s".
+func sessionSummary(start, end []StatsRecord, elapsed time.Duration) string {
+	var packets, bytes uint64
+
+	for i := range end {
+		for act, rec := range end[i].Records {
+			first := start[i].record(act).total
+
+			// a counter below its baseline was reset during the session
+			if rec.total.rxPackets >= first.rxPackets {
+				packets += rec.total.rxPackets - first.rxPackets
+			} else {
+				packets += rec.total.rxPackets
+			}
+
+			if rec.total.rxBytes >= first.rxBytes {
+				bytes += rec.total.rxBytes - first.rxBytes
+			} else {
+				bytes += rec.total.rxBytes
+			}
+		}
+	}
+
+	return fmt.Sprintf("Session: %s across %s packets over %s",
+		formatBytes(bytes), formatThousands(packets), elapsed.Round(time.Second))
+}
+
+// formatThousands formats n with commas between groups of three digits
+func formatThousands(n uint64) string {
+	s := strconv.FormatUint(n, 10)
+
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+
+	return s
+}
@@ -0,0 +1,500 @@
+package stats
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OutputSink receives the samples of a session. Write is called for every
+// action and group on each tick, from the goroutine collecting the stats, so a
+// sink doing slow I/O holds up sampling and should buffer or hand the work to
+// a goroutine of its own. Samples not matching Options.Filter are not written.
+//
+// RenderStats and Stream never close sinks: Close is up to whoever created
+// the sink, once the session is over.
+type OutputSink interface {
+	Write(Sample) error
+	Close() error
+}
+
+// SinkFactory creates a sink from the argument following the sink name in a
+// sink spec, which is empty if the spec has none.
+type SinkFactory func(arg string) (OutputSink, error)
+
+var (
+	sinksMu sync.Mutex
+	sinks   = map[string]SinkFactory{
+		"json":       newJSONFileSink,
+		"csv":        newCSVFileSink,
+		"prometheus": newPrometheusSinkFactory,
+		"journald":   newJournaldSinkFactory,
+		"statsd":     newStatsdSinkFactory,
+		"otlp":       newOTLPSinkFactory,
+		"exec":       newExecSinkFactory,
+	}
+
+	// sinkChecks check the arguments of the built-in sinks taking one, so
+	// that a spec is checked without opening its sink
+	sinkChecks = map[string]func(arg string) error{
+		"prometheus": checkPrometheusAddr,
+		"statsd":     checkStatsdAddr,
+		"otlp":       func(endpoint string) error { _, err := otlpURL(endpoint); return err },
+		"exec":       checkExecCommand,
+	}
+)
+
+// RegisterSink makes a sink available to NewSink under name, replacing any
+// sink registered with the same name.
+func RegisterSink(name string, factory SinkFactory) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	sinks[name] = factory
+}
+
+// Sinks returns the names of the registered sinks.
+func Sinks() []string {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NewSink creates a registered sink from a spec of the form
+// name[:arg][,option...], the options setting the sink's SinkFormat as
+// ParseSinkFormat reads them, e.g. "csv:out.csv,units=bytes,precision=2".
+// The built-in sinks are:
+//
+//	json[:path]       newline delimited JSON, to stdout or appended to path
+//	csv[:path]        CSV with a header row, to stdout or appended to path
+//	prometheus:addr   Prometheus metrics served at http://addr/metrics, addr
+//	                  being host:port or unix:/path
+//	journald          entries in the systemd journal
+//	statsd:addr       StatsD metrics sent over UDP to addr every tick
+//	otlp:url          OpenTelemetry metrics exported over OTLP/HTTP to url
+//	                  every tick
+//	exec:command      command run every tick with the JSON samples on stdin
+//
+// json and csv files whose path ends in .gz are gzip compressed, flushed on
+// every tick.
+func NewSink(spec string) (OutputSink, error) {
+	s, err := ParseSinkSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Open()
+}
+
+// SinkSpec is a sink spec as NewSink takes it, parsed and checked but not
+// opened yet, so that mistakes are caught before a session starts.
+type SinkSpec struct {
+	Name, Arg string
+
+	// Format is the format set by the options of the spec, nil if it has
+	// none
+	Format *SinkFormat
+}
+
+// ParseSinkSpec parses and validates a sink spec of the form
+// name[:arg][,option...], as NewSink takes it.
+func ParseSinkSpec(spec string) (SinkSpec, error) {
+	spec, options, hasOptions := strings.Cut(spec, ",")
+	name, arg, _ := strings.Cut(spec, ":")
+
+	s := SinkSpec{Name: name, Arg: arg}
+
+	if hasOptions {
+		format, err := ParseSinkFormat(options)
+		if err != nil {
+			return SinkSpec{}, fmt.Errorf("sink %s: %w", name, err)
+		}
+
+		s.Format = &format
+	}
+
+	if err := s.Validate(); err != nil {
+		return SinkSpec{}, err
+	}
+
+	return s, nil
+}
+
+// Validate checks that the sink is registered and, for the built-in sinks,
+// that its argument and format are ones it takes.
+func (s SinkSpec) Validate() error {
+	sinksMu.Lock()
+	_, ok := sinks[s.Name]
+	check := sinkChecks[s.Name]
+	sinksMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown sink %q (known sinks: %s)", s.Name, strings.Join(Sinks(), ", "))
+	}
+
+	if check != nil {
+		if err := check(s.Arg); err != nil {
+			return fmt.Errorf("sink %s: %w", s.Name, err)
+		}
+	}
+
+	if s.Format != nil && s.Format.textual() && s.Name != "csv" {
+		return fmt.Errorf("sink %s: units=human and grouping are only supported by csv", s.Name)
+	}
+
+	return nil
+}
+
+// Open creates the sink.
+func (s SinkSpec) Open() (OutputSink, error) {
+	sinksMu.Lock()
+	factory, ok := sinks[s.Name]
+	sinksMu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown sink %q (known sinks: %s)", s.Name, strings.Join(Sinks(), ", "))
+	}
+
+	sink, err := factory(s.Arg)
+	if err != nil {
+		return nil, fmt.Errorf("sink %s: %w", s.Name, err)
+	}
+
+	if s.Format == nil {
+		return sink, nil
+	}
+
+	formattedSink, err := FormatSink(sink, *s.Format)
+	if err != nil {
+		sink.Close()
+		return nil, fmt.Errorf("sink %s: %w", s.Name, err)
+	}
+
+	return formattedSink, nil
+}
+
+// writeSinks hands the samples matching the filter to every sink, once per
+// emit window if EmitInterval is set, and reports whether the tick was
+// written: false for ticks closing no emit window.
+func (o *Options) writeSinks(samples []Sample) (bool, error) {
+	samples = o.emitWindows(samples)
+	if len(samples) == 0 {
+		return false, nil
+	}
+
+	for _, s := range samples {
+		if !o.Filter.Match(s) {
+			continue
+		}
+
+		for _, sink := range o.Sinks {
+			if err := sink.Write(s); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	for _, sink := range o.Sinks {
+		if f, ok := sink.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// bufferedSink hands samples to a sink through a bounded queue drained by a
+// goroutine of its own, so a slow sink doesn't hold up sampling
+type bufferedSink struct {
+	sink  OutputSink
+	queue chan sinkItem
+	drop  bool
+	done  chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	dropped int
+}
+
+func newBufferedSink(sink OutputSink, size int, drop bool) *bufferedSink {
+	b := &bufferedSink{sink: sink, queue: make(chan sinkItem, size), drop: drop, done: make(chan struct{})}
+
+	go func() {
+		defer close(b.done)
+
+		f, flushes := sink.(flusher)
+
+		an, annotates := sink.(annotator)
+
+		for item := range b.queue {
+			var err error
+
+			switch {
+			case item.annotation == nil:
+				err = b.sink.Write(item.sample)
+			case annotates:
+				err = an.Annotate(*item.annotation)
+			}
+
+			// flushed whenever it catches up rather than on every tick
+			if err == nil && flushes && len(b.queue) == 0 {
+				err = f.Flush()
+			}
+
+			if err != nil {
+				b.mu.Lock()
+				if b.err == nil {
+					b.err = err
+				}
+				b.mu.Unlock()
+			}
+		}
+	}()
+
+	return b
+}
+
+// sinkItem is a sample, or an annotation if set, queued for a sink
+type sinkItem struct {
+	sample     Sample
+	annotation *Annotation
+}
+
+// Write queues s, returning the first error the sink returned so far
+func (b *bufferedSink) Write(s Sample) error {
+	return b.push(sinkItem{sample: s})
+}
+
+// Annotate queues a along with the samples, so they stay in order.
+func (b *bufferedSink) Annotate(a Annotation) error {
+	return b.push(sinkItem{annotation: &a})
+}
+
+func (b *bufferedSink) push(item sinkItem) error {
+	b.mu.Lock()
+	err := b.err
+	b.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if !b.drop {
+		b.queue <- item
+		return nil
+	}
+
+	select {
+	case b.queue <- item:
+	default:
+		b.mu.Lock()
+		b.dropped++
+		b.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Close waits for the queued samples to be written. The wrapped sink is left
+// open for its creator to close.
+func (b *bufferedSink) Close() error {
+	close(b.queue)
+	<-b.done
+
+	return nil
+}
+
+// bufferSinks puts a queue of SinkBuffer samples in front of every sink. The
+// returned function drains the queues and logs the samples dropped.
+func (o *Options) bufferSinks() func() {
+	if o.SinkBuffer <= 0 {
+		return func() {}
+	}
+
+	buffered := make([]*bufferedSink, 0, len(o.Sinks))
+	sinks := make([]OutputSink, 0, len(o.Sinks))
+
+	for _, sink := range o.Sinks {
+		b := newBufferedSink(sink, o.SinkBuffer, o.DropOnBackpressure)
+		buffered = append(buffered, b)
+		sinks = append(sinks, b)
+	}
+
+	o.Sinks = sinks
+
+	return func() {
+		for i, b := range buffered {
+			b.Close()
+
+			if b.dropped > 0 {
+				warn(warnSinkDrop, b.dropped)
+				log.Printf("sink %d fell behind, %d samples were dropped\n", i+1, b.dropped)
+			}
+		}
+	}
+}
+
+// flusher is implemented by sinks buffering what they write, flushed once
+// the samples of every tick are written
+type flusher interface {
+	Flush() error
+}
+
+// sinkFile is the destination of a file sink
+type sinkFile struct {
+	io.Writer
+
+	// flush writes out the data compressed so far
+	flush func() error
+	close func() error
+
+	// empty is set for stdout and files that were empty when opened
+	empty bool
+}
+
+func writerFile(w io.Writer) *sinkFile {
+	noop := func() error { return nil }
+	return &sinkFile{Writer: w, flush: noop, close: noop, empty: true}
+}
+
+// openSinkFile returns stdout if path is empty, or path opened for appending.
+// Paths ending in .gz are gzip compressed, each session appending a gzip
+// member of its own. Closing the returned file does not close stdout.
+func openSinkFile(path string) (*sinkFile, error) {
+	if path == "" {
+		return writerFile(os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	file := &sinkFile{Writer: f, flush: func() error { return nil }, close: f.Close, empty: info.Size() == 0}
+
+	if strings.HasSuffix(path, ".gz") {
+		file.compress()
+	}
+
+	return file, nil
+}
+
+// compress gzip compresses what is written to f from now on. Closing f
+// closes the gzip member, writing its trailer, before the file.
+func (f *sinkFile) compress() {
+	gz := gzip.NewWriter(f.Writer)
+	closeFile := f.close
+
+	f.Writer = gz
+	f.flush = gz.Flush
+	f.close = func() error {
+		if err := gz.Close(); err != nil {
+			closeFile()
+			return err
+		}
+
+		return closeFile()
+	}
+}
+
+type jsonSink struct {
+	enc  *json.Encoder
+	file *sinkFile
+}
+
+// NewJSONSink returns a sink writing samples to w as newline delimited JSON.
+func NewJSONSink(w io.Writer) OutputSink {
+	return &jsonSink{enc: json.NewEncoder(w), file: writerFile(w)}
+}
+
+func newJSONFileSink(path string) (OutputSink, error) {
+	file, err := openSinkFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonSink{enc: json.NewEncoder(file), file: file}, nil
+}
+
+func (j *jsonSink) Write(s Sample) error { return j.enc.Encode(s) }
+func (j *jsonSink) Flush() error         { return j.file.flush() }
+func (j *jsonSink) Close() error         { return j.file.close() }
+
+type csvSink struct {
+	w      *csv.Writer
+	header bool
+	file   *sinkFile
+	format SinkFormat
+}
+
+// NewCSVSink returns a sink writing samples to w as CSV, starting with a
+// header row naming the fields.
+func NewCSVSink(w io.Writer) OutputSink {
+	return &csvSink{w: csv.NewWriter(w), header: true, file: writerFile(w)}
+}
+
+func newCSVFileSink(path string) (OutputSink, error) {
+	file, err := openSinkFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// only new files get a header, so appending keeps a single one
+	return &csvSink{w: csv.NewWriter(file), header: file.empty, file: file}, nil
+}
+
+func (c *csvSink) Write(s Sample) error {
+	if c.header {
+		c.header = false
+
+		if err := c.w.Write(csvHeader()); err != nil {
+			return err
+		}
+	}
+
+	if err := c.w.Write(csvRecord(s, c.format)); err != nil {
+		return err
+	}
+
+	// flush every sample so a killed process loses nothing already sampled
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+func (c *csvSink) Flush() error           { return c.file.flush() }
+func (c *csvSink) setFormat(f SinkFormat) { c.format = f }
+
+func (c *csvSink) Close() error {
+	c.w.Flush()
+
+	if err := c.w.Error(); err != nil {
+		c.file.close()
+		return err
+	}
+
+	return c.file.close()
+}
@@ -0,0 +1,48 @@
+package stats
+
+import "testing"
+
+func TestParseSinkSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		ok   bool
+	}{
+		{"json", true},
+		{"csv:out.csv,units=human", true},
+		{"prometheus:localhost:9100", true},
+		{"prometheus:unix:/run/nstats.sock", true},
+		{"statsd:localhost:8125", true},
+		{"otlp:http://localhost:4318", true},
+		{"exec:cat", true},
+		{"nope", false},
+		{"prometheus", false},
+		{"prometheus:unix:", false},
+		{"statsd", false},
+		{"statsd:localhost", false},
+		{"otlp:localhost:4318", false},
+		{"exec", false},
+		{"json:out.json,units=human", false},
+		{"csv,precision=x", false},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseSinkSpec(tt.spec)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("ParseSinkSpec(%q): %v, want ok %v", tt.spec, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateLogFile(t *testing.T) {
+	if err := ValidateLogFile("nstats.csv.gz", 0); err != nil {
+		t.Errorf("a compressed log file that isn't rotated: %v", err)
+	}
+
+	if err := ValidateLogFile("nstats.csv.gz", 1<<20); err != nil {
+		t.Errorf("a compressed log file to rotate: %v", err)
+	}
+
+	if err := ValidateLogFile("", 1<<20); err == nil {
+		t.Error("stdout to rotate was taken")
+	}
+}
@@ -0,0 +1,156 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SinkFormat controls how a sink writes the numbers of the samples, so every
+// destination can get them its own way. The zero value writes them in full,
+// the bit rate in bits per second.
+type SinkFormat struct {
+	// Bytes writes the bit rate in bytes per second.
+	Bytes bool
+
+	// Round rounds the rates and the period to Precision decimals.
+	Round     bool
+	Precision int
+
+	// Human and Grouping are only supported by the csv sink. Human writes
+	// the numbers as the table shows them, e.g. "12 Mbit/s", and Grouping
+	// separates the thousands of the totals with commas.
+	Human    bool
+	Grouping bool
+}
+
+// ParseSinkFormat parses comma separated sink options:
+//
+//	units=bits|bytes|human   unit of the bit rate, or numbers as in the table
+//	precision=N              rates and period rounded to N decimals
+//	grouping                 commas between the thousands of the totals
+func ParseSinkFormat(spec string) (SinkFormat, error) {
+	var f SinkFormat
+
+	for _, opt := range strings.Split(spec, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+
+		switch key {
+		case "units":
+			switch value {
+			case "bits":
+			case "bytes":
+				f.Bytes = true
+			case "human":
+				f.Human = true
+			default:
+				return f, fmt.Errorf("invalid units %q, expected bits, bytes or human", value)
+			}
+		case "precision":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return f, fmt.Errorf("invalid precision %q", value)
+			}
+
+			f.Round, f.Precision = true, n
+		case "grouping":
+			f.Grouping = true
+		default:
+			return f, fmt.Errorf("unknown sink option %q (known options: units, precision, grouping)", opt)
+		}
+	}
+
+	return f, nil
+}
+
+// apply returns s with its numbers converted and rounded
+func (f SinkFormat) apply(s Sample) Sample {
+	if f.Bytes {
+		s.BPS /= 8
+	}
+
+	if f.Round {
+		scale := math.Pow(10, float64(f.Precision))
+		round := func(v float64) float64 { return math.Round(v*scale) / scale }
+
+		s.PPS = round(s.PPS)
+		s.BPS = round(s.BPS)
+		s.Period = round(s.Period)
+	}
+
+	return s
+}
+
+// textual reports whether f needs a sink writing text of its own
+func (f SinkFormat) textual() bool {
+	return f.Human || f.Grouping
+}
+
+// formatted is implemented by sinks taking the textual formats
+type formatted interface {
+	setFormat(SinkFormat)
+}
+
+// formatSink hands the samples to a sink converted to its format
+type formatSink struct {
+	sink   OutputSink
+	format SinkFormat
+}
+
+// FormatSink returns a sink writing the samples to sink converted and rounded
+// as f says. It fails if sink doesn't support Human or Grouping when set.
+func FormatSink(sink OutputSink, f SinkFormat) (OutputSink, error) {
+	if f.textual() {
+		t, ok := sink.(formatted)
+		if !ok {
+			return nil, fmt.Errorf("units=human and grouping are only supported by csv")
+		}
+
+		t.setFormat(f)
+	}
+
+	return &formatSink{sink: sink, format: f}, nil
+}
+
+func (s *formatSink) Write(sample Sample) error { return s.sink.Write(s.format.apply(sample)) }
+func (s *formatSink) Close() error              { return s.sink.Close() }
+
+func (s *formatSink) Flush() error {
+	if f, ok := s.sink.(flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+func (s *formatSink) Annotate(a Annotation) error {
+	if an, ok := s.sink.(annotator); ok {
+		return an.Annotate(a)
+	}
+
+	return nil
+}
+
+// csvValue formats the value of the field named name as f says, returning
+// false to leave it to csvRecord
+func (f SinkFormat) csvValue(name string, s Sample) (string, bool) {
+	switch {
+	case f.Human && name == "pps":
+		return fmt.Sprintf("%.0f pps", s.PPS), true
+	case f.Human && name == "bps" && f.Bytes:
+		return strings.TrimSpace(formatRate(s.BPS, "B")), true
+	case f.Human && name == "bps":
+		return strings.TrimSpace(formatRate(s.BPS, "bit")), true
+	case f.Human && name == "bytes":
+		return formatBytes(s.Bytes), true
+	case f.Human && name == "period":
+		return formatPeriod(s.Period), true
+	case f.Grouping && name == "packets":
+		return formatThousands(s.Packets), true
+	case f.Grouping && name == "bytes":
+		return formatThousands(s.Bytes), true
+	}
+
+	return "", false
+}
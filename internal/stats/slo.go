@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sloRe = regexp.MustCompile(`^\s*([^.\s]+)\.([a-z_]+)\s*(>=|<=|==|!=|>|<)\s*(\S+)\s*$`)
+
+// SLO is a target for a field of an action, e.g. the drop rate staying under
+// 50 pps, with the ticks of the session spent meeting it.
+type SLO struct {
+	Spec   string
+	Action uint
+
+	cond condition
+
+	ticks, violations int
+}
+
+// ParseSLO parses a target of the form action.field op number, e.g.
+// "drop.pps<50". Actions are given as in ParseGroup and fields as in
+// ParseFilter.
+func ParseSLO(spec string, names ActionNames) (*SLO, error) {
+	m := sloRe.FindStringSubmatch(spec)
+	if m == nil {
+		return nil, fmt.Errorf("invalid slo %q: expected action.field op number, e.g. drop.pps<50", spec)
+	}
+
+	act, err := names.lookup(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid slo %q: %w", spec, err)
+	}
+
+	field := strings.ToLower(m[2])
+	if _, ok := sampleFields[field]; !ok {
+		return nil, fmt.Errorf("invalid slo %q: unknown field %q (known fields: %s)", spec, m[2], strings.Join(filterFields(), ", "))
+	}
+
+	v, err := strconv.ParseFloat(m[4], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slo %q: %q is not a number", spec, m[4])
+	}
+
+	return &SLO{Spec: strings.TrimSpace(spec), Action: act, cond: condition{field: field, op: m[3], value: v}}, nil
+}
+
+// Compliance returns the percentage of the ticks the target was met, and
+// false before the first tick.
+func (s *SLO) Compliance() (float64, bool) {
+	if s.ticks == 0 {
+		return 0, false
+	}
+
+	return float64(s.ticks-s.violations) / float64(s.ticks) * 100, true
+}
+
+// String describes the compliance, e.g.
+// "drop.pps<50: 98.3% compliant, 2 of 120 ticks in violation".
+func (s *SLO) String() string {
+	c, ok := s.Compliance()
+	if !ok {
+		return s.Spec + ": no ticks"
+	}
+
+	return fmt.Sprintf("%s: %.1f%% compliant, %d of %d ticks in violation", s.Spec, c, s.violations, s.ticks)
+}
+
+// trackSLOs counts the tick against every SLO, in violation if a sample of
+// its action from any source misses the target
+func (o *Options) trackSLOs(samples []Sample) {
+	for _, slo := range o.SLOs {
+		name := o.ActionNames.name(slo.Action)
+		seen, violated := false, false
+
+		for _, s := range samples {
+			if s.Action != name {
+				continue
+			}
+
+			seen = true
+			if !slo.cond.match(sampleFields[slo.cond.field](s)) {
+				violated = true
+			}
+		}
+
+		if !seen {
+			continue
+		}
+
+		slo.ticks++
+		if violated {
+			slo.violations++
+		}
+	}
+}
+
+// slo returns the compliance of the SLOs of the action of s, e.g.
+// "98.3% pps<50"
+func (o *Options) slo(s Sample) string {
+	var cells []string
+
+	for _, slo := range o.SLOs {
+		if o.ActionNames.name(slo.Action) != s.Action {
+			continue
+		}
+
+		target := slo.Spec[strings.Index(slo.Spec, ".")+1:]
+
+		c, ok := slo.Compliance()
+		if !ok {
+			cells = append(cells, "- "+target)
+			continue
+		}
+
+		cells = append(cells, fmt.Sprintf("%.1f%% %s", c, target))
+	}
+
+	return strings.Join(cells, ", ")
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Snapshot is the state of every action at one point in time.
+type Snapshot struct {
+	SchemaVersion int       `json:"schema_version"`
+	Timestamp     time.Time `json:"timestamp"`
+	Samples       []Sample  `json:"samples"`
+}
+
+// newSnapshot returns the snapshot of the samples of one tick, taken when the
+// last of them was read
+func newSnapshot(samples []Sample) Snapshot {
+	snap := Snapshot{
+		SchemaVersion: SchemaVersion,
+		Samples:       samples,
+	}
+
+	for _, s := range samples {
+		if s.Timestamp.After(snap.Timestamp) {
+			snap.Timestamp = s.Timestamp
+		}
+	}
+
+	return snap
+}
+
+// writeSnapshot writes snap as JSON to a timestamped file in dir and returns
+// the path of the file.
+func writeSnapshot(dir string, snap Snapshot) (string, error) {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := filepath.Join(dir, "nstats-"+snap.Timestamp.Format("20060102T150405.000")+".json")
+
+	return name, os.WriteFile(name, append(data, '\n'), 0o644)
+}
+
+// RotateSnapshots writes the latest snapshot of history to dir every interval
+// until ctx is cancelled, keeping the newest keep snapshot files in dir and
+// removing the older ones. Zero keeps every file. Snapshots saved with the 's'
+// key share the directory and count towards keep. Errors are logged rather
+// than ending the rotation.
+func RotateSnapshots(ctx context.Context, history *History, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			last := history.Last(1)
+			if len(last) == 0 {
+				continue
+			}
+
+			if _, err := writeSnapshot(dir, last[0]); err != nil {
+				warn(warnSnapshot, 1)
+				log.Printf("snapshot failed: %v\n", err)
+				continue
+			}
+
+			if keep > 0 {
+				if err := pruneSnapshots(dir, keep); err != nil {
+					log.Printf("pruning snapshots failed: %v\n", err)
+				}
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pruneSnapshots removes all but the newest keep snapshots in dir
+func pruneSnapshots(dir string, keep int) error {
+	names, err := filepath.Glob(filepath.Join(dir, "nstats-*.json"))
+	if err != nil {
+		return err
+	}
+
+	// the timestamps in the names sort chronologically
+	sort.Strings(names)
+
+	for len(names) > keep {
+		if err := os.Remove(names[0]); err != nil {
+			return err
+		}
+
+		names = names[1:]
+	}
+
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by writeSnapshot.
+func LoadSnapshot(path string) (Snapshot, error) {
+	var snap Snapshot
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snap, err
+	}
+
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+
+	if snap.SchemaVersion != SchemaVersion {
+		return snap, fmt.Errorf("snapshot %s has schema version %d, expected %d", path, snap.SchemaVersion, SchemaVersion)
+	}
+
+	return snap, nil
+}
+
+// find returns the sample of the action read from the map named m
+func (s *Snapshot) find(action, m string) (Sample, bool) {
+	for _, sample := range s.Samples {
+		if sample.Action == action && sample.Map == m {
+			return sample, true
+		}
+	}
+
+	return Sample{}, false
+}
@@ -0,0 +1,154 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Source is a stats map read by a session. When a session reads several maps,
+// the samples of each are tagged with its name.
+type Source struct {
+	Name string
+	Map  MapLooker
+}
+
+// collectAll reads the current record of every source
+func (o *Options) collectAll(sources []Source) ([]StatsRecord, error) {
+	records := make([]StatsRecord, len(sources))
+
+	for i, src := range sources {
+		if err := records[i].collectStats(src.Map, o.CPUs, o.clock()); err != nil {
+			return nil, err
+		}
+	}
+
+	return records, nil
+}
+
+// sampleAll samples every source against its previous record, or the oldest
+// record of its rate window, and returns the
+// new records along with the samples of all sources, one source after the
+// other. The tick is discarded if any source reads unstable.
+func (o *Options) sampleAll(sources []Source, prev []StatsRecord) ([]StatsRecord, []Sample, error) {
+	var (
+		records = make([]StatsRecord, len(sources))
+		samples []Sample
+		failed  int
+		lastErr error
+	)
+
+	if o.RateWindow > 0 && len(o.windows) != len(sources) {
+		o.windows = make([]rateWindow, len(sources))
+	}
+
+	for i, src := range sources {
+		base := prev[i]
+		if o.RateWindow > 0 {
+			base = o.windows[i].base(o.clock().Now().Add(-o.RateWindow), prev[i])
+		}
+
+		var zero *StatsRecord
+		if i < len(o.zero) {
+			zero = &o.zero[i]
+		}
+
+		recv, s, err := o.sample(src.Map, base, prev[i], zero)
+		if err != nil && !errors.Is(err, errUnstableRead) && o.KeepGoing && len(sources) > 1 {
+			o.sourceFailed(i, src, err)
+
+			// the next tick is sampled against the last good record
+			records[i] = prev[i]
+			failed++
+			lastErr = err
+
+			continue
+		}
+
+		if err != nil {
+			return prev, nil, err
+		}
+
+		o.sourceFailed(i, src, nil)
+
+		if o.VerifyCounters {
+			o.verifyCounters(src, prev[i], recv)
+		}
+
+		if o.RateWindow > 0 {
+			o.windows[i].push(recv)
+		}
+
+		for j := range s {
+			s[j].Map = src.Name
+		}
+
+		records[i] = recv
+		samples = append(samples, s...)
+	}
+
+	if failed == len(sources) {
+		return prev, nil, fmt.Errorf("every map failed, the last with: %w", lastErr)
+	}
+
+	return records, samples, nil
+}
+
+// sourceFailed records the error of the source at index i, nil once it reads
+// again, logging the changes unless the table is up
+func (o *Options) sourceFailed(i int, src Source, err error) {
+	if o.failed == nil {
+		o.failed = make(map[int]error)
+	}
+
+	_, wasFailed := o.failed[i]
+
+	switch {
+	case err != nil:
+		if !wasFailed {
+			warn(warnMapFailure, 1)
+		}
+
+		if !wasFailed && !o.tui {
+			log.Printf("map %s failed, keeping on with the others: %v\n", src.Name, err)
+		}
+
+		o.failed[i] = err
+	case wasFailed:
+		if !o.tui {
+			log.Printf("map %s is read again\n", src.Name)
+		}
+
+		delete(o.failed, i)
+	}
+}
+
+// failedRows returns a row per failed source for the table, every cell but
+// the action giving the error
+func (o *Options) failedRows(sources []Source) []*stats {
+	var rows []*stats
+
+	for i, src := range sources {
+		err, ok := o.failed[i]
+		if !ok {
+			continue
+		}
+
+		row := &stats{
+			Action:  src.Name + " ERROR",
+			Packets: "-",
+			PPs:     "-",
+			Bytes:   "-",
+			BPs:     "-",
+			Period:  "error: " + err.Error(),
+		}
+
+		if o.Baseline != nil {
+			row.Baseline = "-"
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -3,51 +3,253 @@ package stats
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cilium/ebpf"
-	"github.com/gizak/termui/v3"
-	ui "github.com/gizak/termui/v3"
-	"github.com/gizak/termui/v3/widgets"
 )
 
+// NumActions is the number of XDP actions, one per key starting at
+// XDP_ABORTED. It is the number of actions collected from maps that don't
+// tell how many they hold.
+const NumActions = 5
+
+// MaxActions is the most actions collected from a map, whatever its size.
+const MaxActions = 64
+
 type datarec struct {
 	rxPackets uint64 // packets received
 	rxBytes   uint64 // bytes received
+
+	// counters are the extra counters of the value schema, named by names
+	counters []uint64
+	names    []string
+
+	// schema and width are the Name and Width of the value schema decoding
+	// the value, empty for values not decoded and for sums
+	schema string
+	width  int
 }
 
+func (d datarec) MarshalBinary() ([]byte, error) {
+	w := new(bytes.Buffer)
+
+	if err := binary.Write(w, binary.LittleEndian, d.rxPackets); err != nil {
+		return nil, err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, d.rxBytes); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a value with the ValueSchema of its size: a struct
+// datarec of 64-bit counters, of 32-bit counters when p holds 8 bytes, or a
+// richer value of a schema registered or put to use.
 func (d *datarec) UnmarshalBinary(p []byte) error {
-	r := bytes.NewBuffer(p)
+	s, ok := ValueSchemaFor(len(p))
+	if !ok {
+		return fmt.Errorf("no value schema decodes values of %d bytes", len(p))
+	}
 
-	err := binary.Read(r, binary.LittleEndian, &d.rxPackets)
+	packets, bytes, counters, err := s.Decode(p)
 	if err != nil {
-		return err
+		return fmt.Errorf("value schema %s: %w", s.Name, err)
 	}
 
-	err = binary.Read(r, binary.LittleEndian, &d.rxBytes)
-	if err != nil {
-		return err
+	d.rxPackets, d.rxBytes, d.counters, d.names = packets, bytes, counters, s.Counters
+	d.schema, d.width = s.Name, s.Width
+
+	return nil
+}
+
+// add adds the counters of o to d
+func (d *datarec) add(o datarec) {
+	d.rxPackets += o.rxPackets
+	d.rxBytes += o.rxBytes
+
+	if len(o.counters) == 0 {
+		return
+	}
+
+	if d.counters == nil {
+		d.counters, d.names = make([]uint64, len(o.counters)), o.names
+	}
+
+	for i := 0; i < len(d.counters) && i < len(o.counters); i++ {
+		d.counters[i] += o.counters[i]
+	}
+}
+
+// counterMap returns the extra counters by name, nil if there are none
+func (d datarec) counterMap() map[string]uint64 {
+	if len(d.counters) == 0 {
+		return nil
+	}
+
+	m := make(map[string]uint64, len(d.counters))
+	for i, c := range d.counters {
+		m[d.names[i]] = c
+	}
+
+	return m
+}
+
+const (
+	// datarecSize is the size of a struct datarec of 64-bit counters
+	datarecSize = 16
+	// datarec32Size is the size of a struct datarec of 32-bit counters
+	datarec32Size = 8
+)
+
+// datarec32 is a struct datarec of 32-bit counters, as written to maps with a
+// value size of 8
+type datarec32 struct {
+	RxPackets uint32
+	RxBytes   uint32
+}
+
+// MapLooker is the subset of *ebpf.Map the collector depends on.
+type MapLooker interface {
+	Lookup(key, valueOut interface{}) error
+}
+
+// ValidateMap checks that the map described by info has the layout the
+// collector expects: a per-CPU array keyed by u32 holding struct datarec, of
+// 64-bit or 32-bit counters, or a per-CPU hash keyed by the action as a u8,
+// u16 or u32, or with composite keys of CompositeKeySize bytes. The errors
+// returned wrap ErrMapTypeMismatch.
+func ValidateMap(info *ebpf.MapInfo) error {
+	switch info.Type {
+	case ebpf.PerCPUArray:
+		if info.KeySize != 4 {
+			return fmt.Errorf("%w: unexpected key size %d, expected 4", ErrMapTypeMismatch, info.KeySize)
+		}
+	case ebpf.PerCPUHash:
+		// 8 byte keys are read as composite keys rather than u64 actions
+		if _, err := actionKey(0, info.KeySize); err != nil {
+			return fmt.Errorf("%w: %s %s", ErrMapTypeMismatch, info.Type, err)
+		}
+	default:
+		return fmt.Errorf("%w: unexpected map type %s, expected %s or %s", ErrMapTypeMismatch, info.Type, ebpf.PerCPUArray, ebpf.PerCPUHash)
+	}
+
+	if _, ok := ValueSchemaFor(int(info.ValueSize)); !ok {
+		return fmt.Errorf("%w: unexpected value size %d, expected %d or %d, or the size of a --value-schema", ErrMapTypeMismatch, info.ValueSize, datarecSize, datarec32Size)
+	}
+
+	return nil
+}
+
+// Check reads every action once to confirm the map can be collected from.
+func Check(statsMap MapLooker) error {
+	var rec StatsRecord
+	return rec.collectStats(statsMap, nil, systemClock{})
+}
+
+// Reset zeroes the counters of every action on every CPU. It modifies the map
+// for everything reading it, not just this tool.
+func Reset(statsMap *ebpf.Map) error {
+	// per-CPU values shorter than the number of CPUs are zero padded
+	var zero interface{} = []datarec{{}}
+	switch statsMap.ValueSize() {
+	case datarecSize:
+	case datarec32Size:
+		zero = []datarec32{{}}
+	default:
+		zero = [][]byte{make([]byte, statsMap.ValueSize())}
+	}
+
+	if statsMap.KeySize() == CompositeKeySize {
+		var (
+			key  = make([]byte, CompositeKeySize)
+			keys [][]byte
+		)
+
+		iter := statsMap.Iterate()
+		for iter.Next(&key, new([]datarec)) {
+			keys = append(keys, append([]byte(nil), key...))
+		}
+
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("error listing keys: %w", err)
+		}
+
+		for _, k := range keys {
+			if err := statsMap.Update(k, zero, ebpf.UpdateExist); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+				return fmt.Errorf("error resetting key %x: %w", k, err)
+			}
+		}
+
+		return nil
+	}
+
+	for action := uint32(0); action < uint32(actionCount(statsMap)); action++ {
+		key, err := actionKey(action, statsMap.KeySize())
+		if err != nil {
+			return err
+		}
+
+		// hashes only hold the actions seen so far
+		if err := statsMap.Update(key, zero, ebpf.UpdateExist); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return fmt.Errorf("error resetting %s: %w", action2str(uint(action)), err)
+		}
 	}
 
 	return nil
 }
 
 type record struct {
+	// timestamp keeps the monotonic reading of the clock, if it has one,
+	// which the periods are taken from
 	timestamp time.Time
 	total     datarec
+
+	// perCPU are the values of every CPU, shown with PerCPU, and values
+	// those of the CPUs the total is summed from
+	perCPU []datarec
+	values []datarec
 }
 
 type StatsRecord struct {
-	Records [5]record
+	Records []record
 }
 
-func (rec *StatsRecord) collectStats(sMap *ebpf.Map) error {
-	var action uint32
+// actionCount returns the number of actions to collect from m: the entries of
+// an array, up to MaxActions, or NumActions for hashes, whose entries are
+// their capacity, and for lookers that don't tell
+func actionCount(m MapLooker) int {
+	sized, ok := m.(interface {
+		Type() ebpf.MapType
+		MaxEntries() uint32
+	})
+	if !ok || sized.Type() != ebpf.PerCPUArray || sized.MaxEntries() == 0 {
+		return NumActions
+	}
+
+	if n := sized.MaxEntries(); n < MaxActions {
+		return int(n)
+	}
 
-	for action = 0; action < 5; action++ {
-		if err := getMapVal(action, sMap, rec /* Stats record */); err != nil {
+	return MaxActions
+}
+
+func (rec *StatsRecord) collectStats(sMap MapLooker, cpus CPUSet, clock Clock) error {
+	n := actionCount(sMap)
+
+	// a new slice, so the copies of the previous reads held on to are left
+	// alone
+	rec.Records = make([]record, n)
+
+	for action := uint32(0); action < uint32(n); action++ {
+		if err := getMapVal(action, sMap, rec /* Stats record */, cpus, clock); err != nil {
 			return err
 		}
 	}
@@ -55,32 +257,67 @@ func (rec *StatsRecord) collectStats(sMap *ebpf.Map) error {
 	return nil
 }
 
-// getMapVal collects the total sum of values per key across all the CPUs
-func getMapVal(key uint32, m *ebpf.Map, stat *StatsRecord) error {
-	var (
-		perCpuValues []datarec
-		valueSum     datarec
-	)
+// record returns the record of action i, or a zero record if rec doesn't hold
+// it
+func (rec StatsRecord) record(i int) record {
+	if i < len(rec.Records) {
+		return rec.Records[i]
+	}
+
+	return record{}
+}
+
+// getMapVal collects the total sum of values per key across the CPUs in cpus,
+// stamped with the time told by clock
+func getMapVal(key uint32, m MapLooker, stat *StatsRecord, cpus CPUSet, clock Clock) error {
+	var perCpuValues []datarec
 
-	stat.Records[key].timestamp = time.Now()
+	stat.Records[key].timestamp = clock.Now()
 
 	err := m.Lookup(&key, &perCpuValues)
 	if err != nil {
 		return err
 	}
 
-	// Collecting data for every cpu and sum them up
-	for _, d := range perCpuValues {
-		valueSum.rxPackets += d.rxPackets
-		valueSum.rxBytes += d.rxBytes
+	// Collecting data for the selected cpus and sum them up
+	values, err := cpus.pick(perCpuValues)
+	if err != nil {
+		return err
 	}
 
-	stat.Records[key].total.rxBytes = valueSum.rxBytes
-	stat.Records[key].total.rxPackets = valueSum.rxPackets
+	stat.Records[key].total = sumValues(values)
+	stat.Records[key].perCPU = perCpuValues
+	stat.Records[key].values = values
 
 	return nil
 }
 
+// ActionInfo describes an XDP action for the action reference.
+type ActionInfo struct {
+	Key         uint
+	Name        string
+	Description string
+}
+
+// Actions returns the XDP actions in key order, with what each means and what
+// typically causes it.
+func Actions() []ActionInfo {
+	descriptions := []string{
+		"the program failed or returned an invalid action, the packet is dropped; a bug in the program",
+		"the program dropped the packet; filtering, DDoS mitigation or load shedding",
+		"the packet was passed on to the kernel network stack as usual",
+		"the packet was bounced back out of the interface it came in on; load balancers and reflectors",
+		"the packet was sent to another interface, CPU or AF_XDP socket; forwarding and redirect maps",
+	}
+
+	actions := make([]ActionInfo, 0, len(descriptions))
+	for act, desc := range descriptions {
+		actions = append(actions, ActionInfo{Key: uint(act), Name: action2str(uint(act)), Description: desc})
+	}
+
+	return actions
+}
+
 func action2str(act uint) string {
 	switch act {
 	case 0:
@@ -94,144 +331,1054 @@ func action2str(act uint) string {
 	case 4:
 		return "XDP_REDIRECT"
 	default:
-		log.Panic("invalid input")
+		return fmt.Sprintf("UNKNOWN(%d)", act)
+	}
+}
+
+// str2action is the inverse of action2str. It also accepts the action names
+// without the XDP_ prefix, in any case.
+func str2action(name string) (uint, error) {
+	n := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(n, "XDP_") {
+		n = "XDP_" + n
+	}
+
+	if n == "XDP_ABORTED" {
+		n = "XDP_ABORT"
+	}
+
+	var act uint
+	for act = 0; act < NumActions; act++ {
+		if action2str(act) == n {
+			return act, nil
+		}
 	}
 
-	return ""
+	return 0, fmt.Errorf("unknown action %q", name)
 }
 
 type stats struct {
+	Action  string
 	Packets string
 	PPs     string
 	Bytes   string
 	BPs     string
 	Period  string
+
+	// Baseline is the packet rate relative to the baseline, if there is one
+	Baseline string
+
+	// Idle is how long the packet counter hasn't moved, with ShowIdle
+	Idle string
+
+	// Peak is the peak rates held, with PeakHold
+	Peak string
+
+	// Average is the session average rates, with ShowAverage
+	Average string
+
+	// SLO is the compliance with the SLOs of the action
+	SLO string
+
+	// Counters are the extra counters named by Options.Counters
+	Counters []string
+
+	// action and pps are the action and packet rate of the sample, which
+	// the color of the row is picked by
+	action string
+	pps    float64
+}
+
+// computeBitsPerSec returns the bit rate of bytes transferred over period
+// seconds. A period that isn't positive, two reads at the same time, and a
+// negative byte count, a counter gone backward, have no rate and give zero.
+func computeBitsPerSec(bytes, period float64) float64 {
+	if period <= 0 || bytes < 0 {
+		return 0
+	}
+
+	return bytes * 8 / period
+}
+
+// rateWidth is the width of the numbers of formatRate, and ppsWidth that of
+// formatPPS
+const (
+	rateWidth = 6
+	ppsWidth  = 10
+)
+
+// fitWidth scales v by thousands, from the first of prefixes on, until it
+// fits in width digits, returning it with its prefix
+func fitWidth(v float64, width int, prefixes []string) (float64, string) {
+	limit := math.Pow(10, float64(width))
+
+	i := 0
+	for i < len(prefixes)-1 && math.Round(math.Abs(v)) >= limit {
+		v /= 1000
+		i++
+	}
+
+	return v, prefixes[i]
+}
+
+// ratePrefixes are the decimal prefixes of the rates, at least k, going up
+// far enough that no rate a float64 counter gives overflows the width
+var ratePrefixes = []string{"k", "M", "G", "T", "P", "E", "Z", "Y", "R", "Q"}
+
+// ppsPrefixes are the prefixes of the packet rates too high for ppsWidth
+var ppsPrefixes = []string{"K", "M", "G", "T", "P", "E", "Z", "Y", "R", "Q"}
+
+// formatRate renders a per second rate of unit with the decimal prefix
+// keeping it under a thousand, rounded, e.g. formatRate(2e6, "bit") ->
+// "     2 Mbit/s" and formatRate(10e9, "bit") -> "    10 Gbit/s".
+func formatRate(value float64, unit string) string {
+	v, prefix := fitWidth(value/1000, 3, ratePrefixes)
+
+	return fmt.Sprintf("%*.0f %s%s/s", rateWidth, v, prefix, unit)
+}
+
+// formatPPS renders a packet rate, e.g. formatPPS(1234) -> "      1234 pps".
+// Rates too high for ppsWidth digits get a prefix, a digit giving way to it
+// to keep the width, e.g. " 12000000 Mpps" for 12e12.
+func formatPPS(pps float64) string {
+	if math.Round(pps) < math.Pow(10, ppsWidth) {
+		return fmt.Sprintf("%*.0f pps", ppsWidth, pps)
+	}
+
+	v, prefix := fitWidth(pps/1000, ppsWidth-1, ppsPrefixes)
+
+	return fmt.Sprintf("%*.0f %spps", ppsWidth-1, v, prefix)
 }
 
-func calculateSpeed(bytes, period float64) string {
-	kbps := (bytes * 8) / period / 1000
+// formatRateAuto is formatRate with the precision of formatAuto, e.g.
+// formatRateAuto(350, "bit") -> "0.35 kbit/s"
+func formatRateAuto(value float64, unit string) string {
+	v, prefix := fitWidth(value/1000, 3, ratePrefixes)
 
-	if kbps < 1000 {
-		return fmt.Sprintf("%6.0f Kbits/s", kbps)
-	} else {
-		mbps := kbps / 1000
-		return fmt.Sprintf("%6.0f Mbits/s", mbps)
+	return fmt.Sprintf("%s %s%s/s", formatAuto(v), prefix, unit)
+}
+
+// formatAuto renders v with fewer decimals the larger it is, and commas
+// between thousands once it has none, e.g. 0.35 -> "0.35", 42.31 -> "42.3",
+// 1234567 -> "1,234,567"
+func formatAuto(v float64) string {
+	switch a := math.Abs(v); {
+	case a == 0:
+		return "0"
+	case a < 10:
+		return fmt.Sprintf("%.2f", v)
+	case a < 100:
+		return fmt.Sprintf("%.1f", v)
 	}
+
+	s := formatThousands(uint64(math.Round(math.Abs(v))))
+	if v < 0 {
+		s = "-" + s
+	}
+
+	return s
 }
 
+// roundSigFigs rounds v to n significant figures, e.g. roundSigFigs(340123, 3)
+// -> 340000
+func roundSigFigs(v float64, n int) float64 {
+	if v == 0 || n <= 0 {
+		return v
+	}
+
+	scale := math.Pow(10, float64(n)-math.Ceil(math.Log10(math.Abs(v))))
+
+	return math.Round(v*scale) / scale
+}
+
+// formatSigFigs renders v rounded to n significant figures with an SI prefix,
+// e.g. formatSigFigs(340123, 3) -> "340k" and formatSigFigs(12345, 2) -> "12k"
+func formatSigFigs(v float64, n int) string {
+	v = roundSigFigs(v, n)
+
+	prefixes := []string{"", "k", "M", "G", "T", "P"}
+
+	i := 0
+	for i < len(prefixes)-1 && math.Abs(v) >= 1000 {
+		v /= 1000
+		i++
+	}
+
+	decimals := 0
+	if v != 0 {
+		if d := n - int(math.Floor(math.Log10(math.Abs(v)))+1); d > 0 {
+			decimals = d
+		}
+	}
+
+	return fmt.Sprintf("%.*f%s", decimals, v, prefixes[i])
+}
+
+// scientific renders v in scientific notation with SigFigs significant
+// figures, three by default
+func (o *Options) scientific(v float64) string {
+	if o.SigFigs > 0 {
+		return formatScientific(v, o.SigFigs-1)
+	}
+
+	return formatScientific(v, 2)
+}
+
+// Notation is how the totals and rates of the table are written.
+type Notation string
+
+const (
+	// NotationSI writes numbers with prefixes and units, e.g. "12 MiB".
+	NotationSI Notation = "si"
+	// NotationScientific writes numbers in plain scientific notation, e.g.
+	// "1.23e9".
+	NotationScientific Notation = "scientific"
+)
+
+// ParseNotation parses a notation name, the empty name being NotationSI.
+func ParseNotation(name string) (Notation, error) {
+	switch n := Notation(name); n {
+	case "":
+		return NotationSI, nil
+	case NotationSI, NotationScientific:
+		return n, nil
+	default:
+		return "", fmt.Errorf("invalid notation %q: expected si or scientific", name)
+	}
+}
+
+// formatScientific renders v in scientific notation with the given number of
+// decimals and a bare exponent, e.g. formatScientific(1234567890, 2) ->
+// "1.23e9"
+func formatScientific(v float64, decimals int) string {
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(v, 'e', decimals, 64), "e")
+
+	sign := ""
+	if exp[0] == '-' {
+		sign = "-"
+	}
+
+	exp = strings.TrimLeft(exp[1:], "0")
+	if exp == "" {
+		return mantissa
+	}
+
+	return mantissa + "e" + sign + exp
+}
+
+// byteUnits are the binary units of formatBytes
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// formatBytes renders a byte count in binary units, rounded to a decimal
+// under ten and to units above, e.g. 512 -> "512 B", 1536 -> "1.5 KiB" and
+// 187e6 -> "178 MiB"
 func formatBytes(bytes uint64) string {
-	kbs := bytes / 1024
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
 
-	if kbs < 1024 {
-		return fmt.Sprintf("%d KBs", kbs)
-	} else {
-		mbs := kbs / 1024
-		return fmt.Sprintf("%d MBs", mbs)
+	v, i := float64(bytes), 0
+	for i < len(byteUnits)-1 && math.Round(v) >= 1024 {
+		v /= 1024
+		i++
 	}
+
+	if math.Round(v*10) < 100 {
+		return fmt.Sprintf("%.1f %s", v, byteUnits[i])
+	}
+
+	return fmt.Sprintf("%.0f %s", v, byteUnits[i])
 }
 
-func calcStats(prev, recv StatsRecord) [5]*stats {
-	var (
-		pps float64
-	)
+// formatCompact renders a count with an SI suffix and one decimal once it
+// reaches a thousand, e.g. 999 -> "999", 1000 -> "1.0k", 1000000 -> "1.0M"
+func formatCompact(n uint64) string {
+	if n < 1000 {
+		return fmt.Sprintf("%d", n)
+	}
 
-	s := [5]*stats{}
+	v := float64(n)
+	suffixes := []string{"", "k", "M", "G", "T", "P", "E"}
 
-	for i := 0; i < 5; i++ {
-		rec := recv.Records[i]
-		prev := prev.Records[i]
+	i := 0
+	// compare against the rounded value so 999950 becomes "1.0M", not "1000.0k"
+	for i < len(suffixes)-1 && math.Round(v*10)/10 >= 1000 {
+		v /= 1000
+		i++
+	}
 
-		period := rec.timestamp.Sub(prev.timestamp).Seconds()
+	return fmt.Sprintf("%.1f%s", v, suffixes[i])
+}
 
-		pps = float64(rec.total.rxPackets-prev.total.rxPackets) / period
+// trendTolerance is the relative change of a rate below which its trend is
+// shown as flat, so that noise doesn't make the arrows flicker
+const trendTolerance = 0.05
 
-		bytes := float64(rec.total.rxBytes - prev.total.rxBytes)
-		speed := calculateSpeed(bytes, period)
+// trend returns a glyph telling whether the rate went up, down or stayed flat
+// since the previous tick
+func trend(cur, prev float64) string {
+	switch {
+	case math.Abs(cur-prev) <= trendTolerance*math.Max(cur, prev):
+		return "▬"
+	case cur > prev:
+		return "▲"
+	default:
+		return "▼"
+	}
+}
 
-		stat := &stats{
-			Packets: fmt.Sprintf("%d", rec.total.rxPackets),
-			PPs:     fmt.Sprintf("%10.0f pps", pps),
-			Bytes:   formatBytes(rec.total.rxBytes),
-			BPs:     speed,
-			Period:  fmt.Sprintf("%f", period),
+// formatDelta formats the change from base to cur as a percentage of base, or
+// as a rate when base is zero
+func formatDelta(cur, base float64) string {
+	if base == 0 {
+		return fmt.Sprintf("%+.0f pps", cur)
+	}
+
+	return fmt.Sprintf("%+.1f%%", (cur-base)/base*100)
+}
+
+// formatPeriod renders a period given in seconds as a duration rounded to the
+// millisecond, e.g. "1.002s" or "500ms"
+func formatPeriod(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	return d.Round(time.Millisecond).String()
+}
+
+// calcStats computes the per action rates between two records
+func calcStats(prev, recv StatsRecord) []Sample {
+	s := make([]Sample, 0, len(recv.Records))
+
+	for i, rec := range recv.Records {
+		// an action the previous record lacks counts from this one
+		last := rec
+		if i < len(prev.Records) {
+			last = prev.Records[i]
+		}
+
+		period := rec.timestamp.Sub(last.timestamp).Seconds()
+
+		// two reads at the same time have no rates rather than infinite
+		// ones, and neither have counters reset in between
+		var pps, bps float64
+		if packets, bytes, ok := recordDelta(last, rec); ok && period > 0 {
+			pps = float64(packets) / period
+			bps = computeBitsPerSec(float64(bytes), period)
 		}
 
-		s[i] = stat
+		s = append(s, Sample{
+			SchemaVersion: SchemaVersion,
+			Timestamp:     rec.timestamp,
+			Action:        action2str(uint(i)),
+			Packets:       rec.total.rxPackets,
+			PPS:           pps,
+			Bytes:         rec.total.rxBytes,
+			BPS:           bps,
+			Period:        period,
+			Counters:      rec.total.counterMap(),
+		})
 	}
 
 	return s
 }
 
-func RenderStats(statsMap *ebpf.Map) error {
-	if err := ui.Init(); err != nil {
-		return err
+// recordDelta returns how much the packet and byte counters of an action grew
+// from prev to cur, or false if they were reset in between. 32-bit counters
+// wrap on each CPU on its own, widened before being summed, so their growth is
+// taken CPU by CPU, modulo 2^32, and then summed, a CPU coming online counting
+// from zero; a wrap is never taken for a reset. 64-bit counters don't wrap, so
+// their totals going backward means the map was cleared or recreated, by a
+// reload for instance. Values decoded by another schema than before are a
+// map recreated too, their counters not being comparable.
+func recordDelta(prev, cur record) (packets, bytes uint64, ok bool) {
+	if len(prev.values) > 0 && len(cur.values) > 0 {
+		if prev.values[0].schema != cur.values[0].schema {
+			return 0, 0, false
+		}
+
+		if narrow(prev.values[0], cur.values[0]) {
+			for i, v := range cur.values {
+				last := datarec{width: v.width}
+				if i < len(prev.values) {
+					last = prev.values[i]
+				}
+
+				p, b := valueDelta(last, v)
+				packets += p
+				bytes += b
+			}
+
+			return packets, bytes, true
+		}
+	}
+
+	if cur.total.rxPackets < prev.total.rxPackets || cur.total.rxBytes < prev.total.rxBytes {
+		return 0, 0, false
+	}
+
+	return cur.total.rxPackets - prev.total.rxPackets, cur.total.rxBytes - prev.total.rxBytes, true
+}
+
+// narrow reports whether two values both hold 32-bit counters
+func narrow(prev, cur datarec) bool {
+	return prev.width == 32 && cur.width == 32
+}
+
+// valueDelta returns how much the packet and byte counters of a value grew
+// from prev to cur, modulo 2^32 for 32-bit counters, which wrap, and as
+// counterDelta does for 64-bit ones
+func valueDelta(prev, cur datarec) (packets, bytes uint64) {
+	if narrow(prev, cur) {
+		return uint64(uint32(cur.rxPackets - prev.rxPackets)), uint64(uint32(cur.rxBytes - prev.rxBytes))
 	}
-	defer ui.Close()
 
-	table := widgets.NewTable()
-	table.Rows = [][]string{
-		[]string{"Action", "Total Packets", "Packets Per Sec", "Total Bytes", "Speed (Mbps)", "Period"},
-		[]string{"", "", "", "", "", ""},
-		[]string{"", "", "", "", "", ""},
-		[]string{"", "", "", "", "", ""},
-		[]string{"", "", "", "", "", ""},
-		[]string{"", "", "", "", "", ""},
+	return counterDelta(prev.rxPackets, cur.rxPackets), counterDelta(prev.rxBytes, cur.rxBytes)
+}
+
+// counterDelta returns how much a 64-bit counter grew from prev to cur, or
+// zero if it went backward, having been reset rather than wrapped
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return 0
 	}
 
-	table.TextStyle = ui.NewStyle(ui.ColorWhite)
-	table.SetRect(0, 0, 120, 13)
-	table.BorderStyle = ui.NewStyle(ui.ColorCyan)
-	table.RowSeparator = true
-	table.FillRow = true
-	table.TextAlignment = termui.AlignCenter
+	return cur - prev
+}
 
-	uiEvents := ui.PollEvents()
+// totalBPS returns the bit rate of all actions together
+func totalBPS(actions []Sample) float64 {
+	var bps float64
+	for _, s := range actions {
+		bps += s.BPS
+	}
 
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+	return bps
+}
 
-	for {
-		var (
-			recv StatsRecord
-			prev StatsRecord
-		)
+// highest returns the largest of values, zero if there are none
+func highest(values []float64) float64 {
+	var p float64
+	for _, v := range values {
+		if v > p {
+			p = v
+		}
+	}
+
+	return p
+}
+
+// dropPercent returns the packets dropped over the period as a percentage of
+// the packets seen by all actions
+func dropPercent(actions []Sample) float64 {
+	var drop, total float64
+
+	for i, s := range actions {
+		total += s.PPS
+
+		if i == 1 /* XDP_DROP */ {
+			drop = s.PPS
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return drop / total * 100
+}
 
-		if err := recv.collectStats(statsMap); err != nil {
-			return fmt.Errorf("error collecting stats: %w", err)
+// formatStats turns samples into the strings displayed in the table
+func (o *Options) formatStats(samples []Sample) []*stats {
+	s := make([]*stats, 0, len(samples))
+
+	for _, sample := range samples {
+		action := sample.Action
+		if sample.Map != "" {
+			action = sample.Map + " " + action
+		}
+
+		stat := &stats{
+			Action:  action,
+			Packets: fmt.Sprintf("%d", sample.Packets),
+			PPs:     formatPPS(sample.PPS),
+			Bytes:   formatBytes(sample.Bytes),
+			BPs:     formatRate(sample.BPS, "bit"),
+			Period:  formatPeriod(sample.Period),
+			action:  sample.Action,
+			pps:     sample.PPS,
 		}
 
-		select {
-		case <-ticker.C:
-			copy(prev.Records[:], recv.Records[:])
+		if o.warming {
+			stat.PPs = "warming up"
+			stat.BPs = "warming up"
+		}
+
+		if o.SigFigs > 0 && !o.warming {
+			stat.PPs = formatSigFigs(sample.PPS, o.SigFigs) + " pps"
+			stat.BPs = formatSigFigs(sample.BPS, o.SigFigs) + " bit/s"
+		}
 
-			if err := recv.collectStats(statsMap); err != nil {
-				return fmt.Errorf("error collecting stats: %w", err)
+		if o.PrecisionAuto && !o.warming {
+			stat.PPs = formatAuto(sample.PPS) + " pps"
+			stat.BPs = formatRateAuto(sample.BPS, "bit")
+		}
+
+		if o.CompactNumbers {
+			stat.Packets = formatCompact(sample.Packets)
+			stat.Bytes = formatCompact(sample.Bytes) + "B"
+		}
+
+		if o.Notation == NotationScientific {
+			stat.Packets = o.scientific(float64(sample.Packets))
+			stat.Bytes = o.scientific(float64(sample.Bytes)) + " B"
+
+			if !o.warming {
+				stat.PPs = o.scientific(sample.PPS) + " pps"
+				stat.BPs = o.scientific(sample.BPS) + " bit/s"
 			}
+		}
 
-			stats := calcStats(prev, recv)
-			table = updateTable(stats, table)
+		if o.Trend && o.last != nil && !o.warming {
+			if prev, ok := o.last.find(sample.Action, sample.Map); ok {
+				stat.PPs = trend(sample.PPS, prev.PPS) + " " + stat.PPs
+			}
+		}
 
-			ui.Render(table)
+		if o.Baseline != nil {
+			stat.Baseline = "-"
 
-		case e := <-uiEvents:
-			switch e.ID {
-			case "q", "<C-c>":
-				return nil
+			if base, ok := o.Baseline.find(sample.Action, sample.Map); ok {
+				stat.Baseline = formatDelta(sample.PPS, base.PPS)
 			}
 		}
+
+		if o.ShowIdle {
+			stat.Idle = o.idle.idle(sample)
+		}
+
+		if o.PeakHold {
+			stat.Peak = o.peaks.peak(sample)
+		}
+
+		if o.ShowAverage {
+			stat.Average = o.averages.average(sample)
+		}
+
+		if len(o.SLOs) > 0 {
+			stat.SLO = o.slo(sample)
+		}
+
+		for _, name := range o.Counters {
+			c, ok := sample.Counters[name]
+			if !ok {
+				stat.Counters = append(stat.Counters, "-")
+				continue
+			}
+
+			cell := fmt.Sprintf("%d", c)
+			if rate, ok := o.counterRate(sample, name); ok {
+				cell += fmt.Sprintf(" (%.0f/s)", rate)
+			}
+
+			stat.Counters = append(stat.Counters, cell)
+		}
+
+		s = append(s, stat)
+	}
+
+	return s
+}
+
+// DefaultHistorySize is the number of ticks kept when Options.History is not
+// set.
+const DefaultHistorySize = 60
+
+// historySize is the number of ticks the table keeps when Options.History is
+// not set, enough for the graph
+func (o *Options) historySize() int {
+	if o.GraphHistory > DefaultHistorySize {
+		return o.GraphHistory
+	}
+
+	return DefaultHistorySize
+}
+
+// graphHistory is the number of ticks graphed
+func (o *Options) graphHistory() int {
+	if o.GraphHistory > 0 {
+		return o.GraphHistory
+	}
+
+	return DefaultHistorySize
+}
+
+// DefaultInterval is the time between two samples unless Options.Interval
+// says otherwise.
+const DefaultInterval = time.Second
+
+// Options controls how the collected stats are presented.
+type Options struct {
+	// ActionNames overrides the labels of the actions.
+	ActionNames ActionNames
+
+	// Groups are synthetic rows appended after the actions.
+	Groups []Group
+
+	// Baseline, when set, adds a column comparing the packet rate of every
+	// action with its rate in the baseline snapshot.
+	Baseline *Snapshot
+
+	// Ranking, when set, orders the rows of the table by a field instead of
+	// ActionOrder and keeps only the top ones.
+	Ranking *Ranking
+
+	// View selects the columns of the table. Empty shows them all.
+	View View
+
+	// NoRowSeparator and NoFillRow draw a denser table without lines
+	// between the rows, or without filling the rows' background.
+	NoRowSeparator bool
+	NoFillRow      bool
+
+	// CellStacked shows each total and its rate stacked in one two-line cell
+	// rather than in columns of their own, for narrow terminals. It only
+	// applies to ViewBoth.
+	CellStacked bool
+
+	// Layout arranges the columns, the zero value being LayoutFlat. Machine
+	// outputs aren't affected.
+	Layout Layout
+
+	// ActionOrder is the order the table shows the actions in, by key.
+	// Empty keeps the key order.
+	ActionOrder []uint
+
+	// CompactNumbers shows the packet and byte totals with SI suffixes.
+	CompactNumbers bool
+
+	// Notation, when NotationScientific, shows the totals and rates in
+	// scientific notation, taking over CompactNumbers, PrecisionAuto and
+	// SigFigs. SigFigs still sets the number of digits.
+	Notation Notation
+
+	// Label is attached to every sample and shown in the table title.
+	Label string
+
+	// FreezeColumns pads the cells of the table to the widest each column
+	// was so far, so the numbers stay in place from one tick to the next
+	// rather than moving as they gain or lose digits.
+	FreezeColumns bool
+
+	// ColumnWidth is the width the cells are padded to at least, implying
+	// FreezeColumns.
+	ColumnWidth int
+
+	// widths holds the widths of the columns, with FreezeColumns
+	widths *columnWidths
+
+	// MaxWidth caps the width of the table. Zero uses the full terminal
+	// width.
+	MaxWidth int
+
+	// ExtendedStats adds sparklines of the drop percentage and of the bit
+	// rate of all actions below the table.
+	ExtendedStats bool
+
+	// Efficiency adds a gauge of the bytes passed as a share of the bytes
+	// passed and dropped, over the last tick and the session.
+	Efficiency bool
+
+	// Filter restricts the samples written to Sinks. The table shows every
+	// sample regardless.
+	Filter *Filter
+
+	// Sinks receive every sample of every tick, in the table and headless
+	// modes alike.
+	Sinks []OutputSink
+
+	// VerifyCounters reports every counter lower than on the previous read
+	// of its map, as the counters of a healthy program never decrease.
+	// They are logged, or listed once the table closes.
+	VerifyCounters bool
+
+	// violations holds the decreases seen while the table is up
+	violations []string
+
+	// Alerts are reported on stderr, and to the sinks recording
+	// annotations, as they start and stop holding. The table highlights
+	// the actions alerting and lists the reports once it closes.
+	Alerts []*Alert
+
+	// NotifyMinInterval is the least time between two reports of an alert.
+	// Changes of state within it are held back, and the state the alert is
+	// in once it has passed is reported if it differs from the last one
+	// reported, so an alert flapping is reported once per interval at most.
+	// The table highlights the alerts firing as they are.
+	NotifyMinInterval time.Duration
+
+	// alerts holds the reports made while the table is up
+	alerts []AlertEvent
+
+	// Annotations are written to the sinks recording them, the JSON and CSV
+	// ones, between the samples as they come in. Only the headless modes
+	// read them.
+	Annotations <-chan Annotation
+
+	// SinkBuffer, when set, queues up to SinkBuffer samples in front of
+	// every sink, written from a goroutine per sink so slow sinks don't hold
+	// up sampling. A full queue blocks sampling, or with DropOnBackpressure
+	// drops the sample.
+	SinkBuffer         int
+	DropOnBackpressure bool
+
+	// Interface, when set, shows the packets XDP_PASS handed to the stack
+	// next to the receive counters of the interface from /proc/net/dev, both
+	// counted from the start of the session.
+	Interface string
+
+	// StableReads reads the map twice per tick and discards the tick when the
+	// growths of the counters since the previous tick, as seen by the two
+	// reads, differ by more than StableReadsTolerance, a fraction of the
+	// growth seen by the second read.
+	StableReads          bool
+	StableReadsTolerance float64
+
+	// History, when set, receives a snapshot of every tick so other
+	// consumers can read the recent samples while the session runs.
+	History *History
+
+	// RelativeTimestamps sets the Elapsed field of every sample to the time
+	// since the session started.
+	RelativeTimestamps bool
+
+	// SnapshotDir is where the 's' key writes snapshots. Defaults to the
+	// working directory.
+	SnapshotDir string
+
+	// Interval is the time between two samples. Zero uses DefaultInterval.
+	Interval time.Duration
+
+	// IntervalJitter moves every tick by a random amount of up to
+	// IntervalJitter either way, so hosts started together do not all sample
+	// at once. The rates are unaffected as they use the actual period.
+	IntervalJitter time.Duration
+
+	// Count ends Stream once the samples of Count ticks are written, the
+	// first tick coming one Interval after the start so its rates span a
+	// whole period. With EmitInterval it counts the emit windows written.
+	// Zero streams until the context is cancelled.
+	Count int
+
+	// EmitInterval, when longer than Interval, writes a sample of every
+	// action to the sinks once per EmitInterval rather than on every tick,
+	// its rates taken over the whole EmitInterval. The table and the history
+	// keep every tick.
+	EmitInterval time.Duration
+
+	// emitStart holds the sample opening the emit window of every series
+	emitStart map[string]Sample
+
+	// MinPacketSize highlights the rows whose average packet size over the
+	// last period falls below it while they see traffic, as floods of small
+	// packets do. Zero disables the check.
+	MinPacketSize float64
+
+	// RateWindow computes the rates across the samples of the last
+	// RateWindow instead of the last tick alone, smoothing them out. The
+	// period of the samples is the span of the window.
+	RateWindow time.Duration
+
+	// CPUs restricts the per-CPU values summed to those of the CPUs in the
+	// set. Nil sums every CPU.
+	CPUs CPUSet
+
+	// RenderFPS caps how many times a second the table is repainted for new
+	// samples. Zero repaints on every tick.
+	RenderFPS int
+
+	// StaleAfter, when set, keeps the table running when reading the map
+	// fails, dimming it and showing the age of the samples once they are
+	// older than StaleAfter. Without it the first failed read ends the
+	// session.
+	StaleAfter time.Duration
+
+	// Warmup is how long after the start the rates are not trusted: the
+	// table shows "warming up" in their place and nothing is written to
+	// the sinks or the history. Zero starts with the first tick, one
+	// interval in.
+	Warmup time.Duration
+
+	// warming is set on the ticks within Warmup
+	warming bool
+
+	// Trend marks the packet rates with an arrow telling whether they rose
+	// or fell since the previous tick.
+	Trend bool
+
+	// last is the snapshot of the previous tick, for the trends
+	last *Snapshot
+
+	// PrecisionAuto shows the rates with decimals picked by magnitude,
+	// fractions for small rates and whole numbers for large ones, rather than
+	// rounded to whole numbers.
+	PrecisionAuto bool
+
+	// KeepGoing goes on showing the other maps when one of several fails to
+	// be read, showing it as failed, rather than failing the tick.
+	KeepGoing bool
+
+	// failed holds the errors of the sources failing, by index, with
+	// KeepGoing
+	failed map[int]error
+
+	// tui is set while the table is up, so errors are shown rather than
+	// logged, and screen is the terminal it is drawn on
+	tui    bool
+	screen screen
+
+	// Derived are metrics computed from the samples of several actions,
+	// shown in a table of their own below the stats.
+	Derived []Derived
+
+	// A11y signals alerts with symbols and high-contrast styles rather than
+	// by color alone: "!" marks rows needing attention, "*" stale samples
+	// and arrows the trends.
+	A11y bool
+
+	// SigFigs, when set, rounds the rates shown in the table to that many
+	// significant figures, steadying rates that jitter. Sinks get the rates
+	// in full.
+	SigFigs int
+
+	// NoColor draws the table in the terminal's default colors, keeping
+	// only text attributes such as bold.
+	NoColor bool
+
+	// ShowIdle adds a column telling how long the packet counter of each
+	// action hasn't moved, to spot dead paths.
+	ShowIdle bool
+
+	// HideZero leaves the actions whose totals are zero out of the table,
+	// each showing up again on the first tick it counts a packet.
+	HideZero bool
+
+	// idle tracks when the counters last moved, with ShowIdle
+	idle *idleTracker
+
+	// SLOs are targets for the actions. The ticks spent missing each are
+	// counted, shown in an SLO column and in the session summary.
+	SLOs []*SLO
+
+	// Clock tells the time the counters are read at, time.Now with its
+	// monotonic reading if unset. Tests set one to get exact periods and rates.
+	Clock Clock
+
+	// ExitAfterIdle, when set, ends the session once no packets were
+	// counted for that long, printing the session summary.
+	ExitAfterIdle time.Duration
+
+	// active is when packets were last counted, with ExitAfterIdle
+	active time.Time
+
+	// PeakHold adds a column holding the peak rates of every action for a
+	// couple of seconds before slowly decaying them, so brief spikes stay
+	// readable.
+	PeakHold bool
+
+	// peaks tracks the peak rates, with PeakHold
+	peaks *peakTracker
+
+	// ShowAverage adds a column with the average rates of every action since
+	// the session started, next to the rates of the last period.
+	ShowAverage bool
+
+	// averages tracks the samples the averages start from, with ShowAverage
+	averages *averageTracker
+
+	// Graph adds sparklines of the packet rates of the last GraphHistory
+	// ticks above the table, one per action of GraphActions, or of every
+	// action if it is empty.
+	Graph        bool
+	GraphActions []uint
+	GraphHistory int
+
+	// PerCPU adds a table of the rates of every action on every CPU, of the
+	// first map read, showing how the traffic spreads over the RX queues.
+	PerCPU bool
+
+	// Counters are the extra counters of the value schema shown in columns
+	// of their own, drop reasons for instance.
+	Counters []string
+
+	// ColumnsFromMap, when set, heads the columns after the fields of the
+	// value schema and shows the rate of every extra counter along with
+	// its total. Schemas not naming their fields keep the usual headers.
+	ColumnsFromMap *ValueSchema
+
+	// Metadata are lines describing the maps read, shown in a panel below
+	// the table.
+	Metadata []string
+
+	// CompactHeader, when set, names the maps read in a single line above
+	// the table, along with the interval and the uptime.
+	CompactHeader string
+
+	// ZeroOnStart shows the totals counted since the session started rather
+	// than since the counters were created. The map is left untouched.
+	ZeroOnStart bool
+
+	// zero holds the records of every source the totals are relative to,
+	// with ZeroOnStart
+	zero []StatsRecord
+
+	// windows holds the rate window of every source
+	windows []rateWindow
+
+	// start is when the session started
+	start time.Time
+}
+
+// avgPacketSize returns the average size in bytes of the packets of the last
+// period, and false if there were none
+func avgPacketSize(s Sample) (float64, bool) {
+	if s.PPS == 0 {
+		return 0, false
+	}
+
+	return s.BPS / 8 / s.PPS, true
+}
+
+// smallPackets reports whether the packets of s are smaller on average than
+// MinPacketSize
+func (o *Options) smallPackets(s Sample) bool {
+	size, ok := avgPacketSize(s)
+
+	return o.MinPacketSize > 0 && ok && size < o.MinPacketSize
+}
+
+// tickInterval returns the time until the next tick
+func (o *Options) tickInterval() time.Duration {
+	interval := o.interval()
+
+	if o.IntervalJitter <= 0 {
+		return interval
+	}
+
+	return interval - o.IntervalJitter + time.Duration(rand.Int63n(int64(2*o.IntervalJitter)+1))
+}
+
+// sample collects a new record and returns it along with the samples computed
+// against prev, the previous read or the oldest of the rate window. With
+// StableReads the two reads are checked against last, the previous read. The
+// totals of the samples are taken relative to zero if it is set.
+func (o *Options) sample(statsMap MapLooker, prev, last StatsRecord, zero *StatsRecord) (StatsRecord, []Sample, error) {
+	var recv StatsRecord
+
+	if err := recv.collectStats(statsMap, o.CPUs, o.clock()); err != nil {
+		return recv, nil, fmt.Errorf("error collecting stats: %w", err)
+	}
+
+	if o.StableReads {
+		first := recv
+
+		if err := recv.collectStats(statsMap, o.CPUs, o.clock()); err != nil {
+			return recv, nil, fmt.Errorf("error collecting stats: %w", err)
+		}
+
+		if !stable(last, first, recv, o.StableReadsTolerance) {
+			warn(warnStaleRead, 1)
+			return prev, nil, errUnstableRead
+		}
+	}
+
+	countReset(last, recv)
+
+	samples := calcStats(prev, recv)
+
+	for i := range samples {
+		samples[i].Action = o.ActionNames.name(uint(i))
+
+		if zero != nil {
+			samples[i].Packets = sinceZero(samples[i].Packets, zero.record(i).total.rxPackets)
+			samples[i].Bytes = sinceZero(samples[i].Bytes, zero.record(i).total.rxBytes)
+		}
 	}
+
+	samples = appendGroups(samples, o.Groups)
+
+	for i := range samples {
+		samples[i].Label = o.Label
+
+		if o.RelativeTimestamps {
+			samples[i].Elapsed = samples[i].Timestamp.Sub(o.start).Seconds()
+		}
+	}
+
+	return recv, samples, nil
+}
+
+// countReset counts a counter reset if the counters of any action of recv
+// were reset since last, the previous read, a map cleared or recreated
+// counting once
+func countReset(last, recv StatsRecord) {
+	for i, rec := range recv.Records {
+		if _, _, ok := recordDelta(last.record(i), rec); !ok {
+			warn(warnCounterReset, 1)
+			return
+		}
+	}
+}
+
+// sinceZero returns the count of a counter at total since it was at zero. A
+// total below zero means the counter was reset, and counts from there.
+func sinceZero(total, zero uint64) uint64 {
+	if total < zero {
+		return total
+	}
+
+	return total - zero
 }
 
-func updateTable(stats [5]*stats, table *widgets.Table) *widgets.Table {
-	for i := 0; i < len(stats); i++ {
-		s := stats[i]
-		table.Rows[i+1][0] = action2str(uint(i))
-		table.Rows[i+1][1] = s.Packets
-		table.Rows[i+1][2] = s.PPs
-		table.Rows[i+1][3] = s.Bytes
-		table.Rows[i+1][4] = s.BPs
-		table.Rows[i+1][5] = s.Period
+// errUnstableRead is returned by sample when two back-to-back reads disagree
+// and the sample is discarded
+var errUnstableRead = errors.New("unstable read")
+
+// stable reports whether two back-to-back reads agree on how much the
+// counters grew since the previous read, last: their growths differ by at
+// most tolerance, relative to that of the second read. Comparing the totals
+// would leave the tolerance to grow with them, letting ever larger
+// disagreements through on counters that have run for long.
+func stable(last, first, second StatsRecord, tolerance float64) bool {
+	within := func(a, b uint64) bool {
+		if b == 0 {
+			return a == 0
+		}
+
+		return math.Abs(float64(b)-float64(a))/float64(b) <= tolerance
+	}
+
+	for i := range second.Records {
+		prev := last.record(i)
+		if i >= len(last.Records) {
+			prev = second.Records[i]
+		}
+
+		p1, b1, ok1 := recordDelta(prev, first.record(i))
+		p2, b2, ok2 := recordDelta(prev, second.Records[i])
+
+		// a reset seen by one read alone happened between them
+		if ok1 != ok2 {
+			return false
+		}
+
+		if !within(p1, p2) || !within(b1, b2) {
+			return false
+		}
 	}
 
-	return table
+	return true
+}
+
+// actions returns the samples of the XDP actions, leaving out the groups
+func (o *Options) actions(samples []Sample) []Sample {
+	return samples[:len(samples)-len(o.Groups)]
 }
@@ -0,0 +1,333 @@
+package stats
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// value encodes a value of size bytes, 16 for 64-bit counters and 8 for
+// 32-bit ones, decoded as the collector does
+func value(t *testing.T, size int, packets, bytes uint64) datarec {
+	t.Helper()
+
+	p := make([]byte, size)
+	if size == datarec32Size {
+		binary.LittleEndian.PutUint32(p, uint32(packets))
+		binary.LittleEndian.PutUint32(p[4:], uint32(bytes))
+	} else {
+		binary.LittleEndian.PutUint64(p, packets)
+		binary.LittleEndian.PutUint64(p[8:], bytes)
+	}
+
+	var d datarec
+	if err := d.UnmarshalBinary(p); err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+// records returns a StatsRecord of one action holding the values of the CPUs
+func records(at time.Time, values ...datarec) StatsRecord {
+	return StatsRecord{Records: []record{{timestamp: at, total: sumValues(values), perCPU: values, values: values}}}
+}
+
+func TestCalcStatsWidths(t *testing.T) {
+	const max32 = 1<<32 - 1
+
+	tests := []struct {
+		name       string
+		size       int
+		prev, cur  [][2]uint64 // packets and bytes per CPU
+		pps, bytes float64
+	}{
+		{
+			name: "64-bit",
+			size: datarecSize,
+			prev: [][2]uint64{{100, 1000}, {200, 2000}},
+			cur:  [][2]uint64{{150, 1500}, {300, 3000}},
+			pps:  150, bytes: 1500,
+		},
+		{
+			name: "64-bit past 2^32",
+			size: datarecSize,
+			prev: [][2]uint64{{max32 - 9, max32 - 99}},
+			cur:  [][2]uint64{{max32 + 11, max32 + 101}},
+			pps:  20, bytes: 200,
+		},
+		{
+			name: "32-bit",
+			size: datarec32Size,
+			prev: [][2]uint64{{100, 1000}, {200, 2000}},
+			cur:  [][2]uint64{{150, 1500}, {300, 3000}},
+			pps:  150, bytes: 1500,
+		},
+		{
+			name: "32-bit wrapping on one CPU",
+			size: datarec32Size,
+			prev: [][2]uint64{{max32 - 9, max32 - 99}, {200, 2000}},
+			cur:  [][2]uint64{{10, 100}, {300, 3000}},
+			pps:  120, bytes: 1200,
+		},
+		{
+			name: "32-bit wrapping on every CPU",
+			size: datarec32Size,
+			prev: [][2]uint64{{max32, max32}, {max32 - 1, max32 - 1}},
+			cur:  [][2]uint64{{4, 4}, {3, 3}},
+			pps:  10, bytes: 10,
+		},
+	}
+
+	start := time.Unix(1700000000, 0)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prev, cur []datarec
+			for _, v := range tt.prev {
+				prev = append(prev, value(t, tt.size, v[0], v[1]))
+			}
+			for _, v := range tt.cur {
+				cur = append(cur, value(t, tt.size, v[0], v[1]))
+			}
+
+			s := calcStats(records(start, prev...), records(start.Add(time.Second), cur...))
+			if len(s) != 1 {
+				t.Fatalf("got %d samples, want 1", len(s))
+			}
+
+			if s[0].PPS != tt.pps {
+				t.Errorf("PPS = %v, want %v", s[0].PPS, tt.pps)
+			}
+
+			if want := tt.bytes * 8; s[0].BPS != want {
+				t.Errorf("BPS = %v, want %v", s[0].BPS, want)
+			}
+		})
+	}
+}
+
+func TestPerCPURateWraps(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	prev := records(start, value(t, datarec32Size, 1<<32-5, 0)).Records[0]
+	cur := records(start.Add(time.Second), value(t, datarec32Size, 5, 0)).Records[0]
+
+	if got, want := perCPURate(prev, cur, 0), "10 pps, 0 kbit/s"; got != want {
+		t.Errorf("perCPURate = %q, want %q", got, want)
+	}
+
+	prev = records(start, value(t, datarecSize, 100, 0)).Records[0]
+	cur = records(start.Add(time.Second), value(t, datarecSize, 5, 0)).Records[0]
+
+	if got := perCPURate(prev, cur, 0); got != "-" {
+		t.Errorf("perCPURate of a reset 64-bit counter = %q, want -", got)
+	}
+}
+
+func TestCalcStatsResets(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		prev, cur datarec
+		pps       float64
+	}{
+		{"64-bit counter going backward", value(t, datarecSize, 1000, 5000), value(t, datarecSize, 10, 50000), 0},
+		{"32-bit counter wrapping", value(t, datarec32Size, 1<<32-10, 5000), value(t, datarec32Size, 10, 50000), 20},
+		{"schema changing", value(t, datarec32Size, 1000, 5000), value(t, datarecSize, 2000, 50000), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calcStats(records(start, tt.prev), records(start.Add(time.Second), tt.cur))
+
+			if s[0].PPS != tt.pps {
+				t.Errorf("PPS = %v, want %v", s[0].PPS, tt.pps)
+			}
+
+			if tt.pps == 0 && s[0].BPS != 0 {
+				t.Errorf("BPS = %v across a reset, want 0", s[0].BPS)
+			}
+		})
+	}
+}
+
+func TestCalcStatsCPUOnline(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	for _, size := range []int{datarecSize, datarec32Size} {
+		prev := records(start, value(t, size, 100, 1000))
+		cur := records(start.Add(time.Second), value(t, size, 150, 1500), value(t, size, 20, 200))
+
+		if s := calcStats(prev, cur); s[0].PPS != 70 {
+			t.Errorf("%d byte values: PPS = %v with a CPU coming online, want 70", size, s[0].PPS)
+		}
+	}
+}
+
+func TestStable(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	read := func(packets uint64) StatsRecord {
+		return records(start, value(t, datarecSize, packets, packets*100))
+	}
+
+	tests := []struct {
+		name                string
+		last, first, second uint64
+		stable              bool
+	}{
+		{"equal reads", 1000, 1100, 1100, true},
+		{"no traffic", 1000, 1000, 1000, true},
+		{"traffic starting between the reads", 1000, 1000, 1001, false},
+		// the totals differ by a millionth, the growths by a tenth
+		{"large totals", 1e9, 1e9 + 100, 1e9 + 110, false},
+		{"within the tolerance", 1e9, 1e9 + 100000, 1e9 + 100050, true},
+		{"reset between the reads", 1000, 1100, 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stable(read(tt.last), read(tt.first), read(tt.second), 0.001); got != tt.stable {
+				t.Errorf("stable = %v, want %v", got, tt.stable)
+			}
+		})
+	}
+}
+
+func TestFormatCompact(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1.0k"},
+		{1049, "1.0k"},
+		{1050, "1.1k"},
+		{999949, "999.9k"},
+		{999950, "1.0M"},
+		{999999, "1.0M"},
+		{1e6, "1.0M"},
+		{1e9, "1.0G"},
+		{1<<64 - 1, "18.4E"},
+	}
+
+	for _, tt := range tests {
+		if got := formatCompact(tt.n); got != tt.want {
+			t.Errorf("formatCompact(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestComputeBitsPerSec(t *testing.T) {
+	tests := []struct {
+		name          string
+		bytes, period float64
+		want          float64
+	}{
+		{"one second", 1000, 1, 8000},
+		{"half a second", 1000, 0.5, 16000},
+		{"no traffic", 0, 1, 0},
+		{"zero period", 1000, 0, 0},
+		{"negative period", 1000, -1, 0},
+		{"counter reset", -1000, 1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := computeBitsPerSec(tt.bytes, tt.period); got != tt.want {
+			t.Errorf("%s: computeBitsPerSec(%v, %v) = %v, want %v", tt.name, tt.bytes, tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestTotalBPS(t *testing.T) {
+	actions := []Sample{{BPS: 1e6}, {BPS: 2e6}, {BPS: 0}}
+
+	if got := totalBPS(actions); got != 3e6 {
+		t.Errorf("totalBPS = %v, want 3e6", got)
+	}
+
+	if got := highest([]float64{1, 5, 3}); got != 5 {
+		t.Errorf("highest = %v, want 5", got)
+	}
+}
+
+func TestFormatRateWidths(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "     0 kbit/s"},
+		{999, "     1 kbit/s"},
+		{999499, "   999 kbit/s"},
+		{999500, "     1 Mbit/s"},
+		{1e9, "     1 Gbit/s"},
+		{1e12, "     1 Tbit/s"},
+		{1e15, "     1 Pbit/s"},
+		{1e18, "     1 Ebit/s"},
+		{1e21, "     1 Zbit/s"},
+		{1e24, "     1 Ybit/s"},
+		{1e27, "     1 Rbit/s"},
+		{1e30, "     1 Qbit/s"},
+		{123e30, "   123 Qbit/s"},
+	}
+
+	for _, tt := range tests {
+		got := formatRate(tt.v, "bit")
+		if got != tt.want {
+			t.Errorf("formatRate(%g) = %q, want %q", tt.v, got, tt.want)
+		}
+
+		if len(got) != len(tt.want) || len(strings.Fields(got)[0]) > rateWidth {
+			t.Errorf("formatRate(%g) = %q overflows %d digits", tt.v, got, rateWidth)
+		}
+	}
+}
+
+func TestFormatRateAuto(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0 kbit/s"},
+		{350, "0.35 kbit/s"},
+		{999500, "1.00 Mbit/s"},
+		{42.31e12, "42.3 Tbit/s"},
+		{1e30, "1.00 Qbit/s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRateAuto(tt.v, "bit"); got != tt.want {
+			t.Errorf("formatRateAuto(%g) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPPSWidths(t *testing.T) {
+	tests := []struct {
+		pps  float64
+		want string
+	}{
+		{0, "         0 pps"},
+		{1234, "      1234 pps"},
+		{9999999999, "9999999999 pps"},
+		{9999999999.5, " 10000000 Kpps"},
+		{12e12, " 12000000 Mpps"},
+		{1e18, "  1000000 Tpps"},
+		{1e24, "  1000000 Epps"},
+		{1e30, "  1000000 Ypps"},
+	}
+
+	for _, tt := range tests {
+		got := formatPPS(tt.pps)
+		if got != tt.want {
+			t.Errorf("formatPPS(%g) = %q, want %q", tt.pps, got, tt.want)
+		}
+
+		if len(got) != len(tt.want) || len(strings.Fields(got)[0]) > ppsWidth {
+			t.Errorf("formatPPS(%g) = %q overflows %d digits", tt.pps, got, ppsWidth)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// statsdMaxPacket is the largest datagram sent, small enough not to be
+// fragmented on common MTUs
+const statsdMaxPacket = 1432
+
+// statsdSink pushes the samples as StatsD metrics tagged DogStatsD style,
+// batching the metrics of a tick into as few datagrams as possible
+type statsdSink struct {
+	conn net.Conn
+
+	// last holds the totals of the previous sample of each series, the
+	// counters sending the increments
+	last map[string]Sample
+
+	batch  bytes.Buffer
+	failed bool
+}
+
+func newStatsdSinkFactory(addr string) (OutputSink, error) {
+	return NewStatsdSink(addr)
+}
+
+// NewStatsdSink returns a sink sending every tick's samples over UDP to the
+// StatsD server at addr: xdp.rx_packets and xdp.rx_bytes counters and
+// xdp.rx_pps and xdp.rx_bps gauges, tagged with the action, label and map.
+// Failed sends are logged, not returned, so a missing agent doesn't stop the
+// session.
+func NewStatsdSink(addr string) (OutputSink, error) {
+	if err := checkStatsdAddr(addr); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statsdSink{conn: conn, last: make(map[string]Sample)}, nil
+}
+
+// checkStatsdAddr checks that addr is a host:port
+func checkStatsdAddr(addr string) error {
+	if addr == "" {
+		return fmt.Errorf("missing server address, expected statsd:host:port")
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("invalid server address %q, expected host:port", addr)
+	}
+
+	return nil
+}
+
+func (d *statsdSink) Write(s Sample) error {
+	tags := statsdTags(s)
+
+	var lines []string
+
+	// counters need a previous total to send the increment since
+	if last, ok := d.last[tags]; ok && s.Packets >= last.Packets && s.Bytes >= last.Bytes {
+		lines = append(lines,
+			fmt.Sprintf("xdp.rx_packets:%d|c|#%s", s.Packets-last.Packets, tags),
+			fmt.Sprintf("xdp.rx_bytes:%d|c|#%s", s.Bytes-last.Bytes, tags))
+	}
+
+	d.last[tags] = s
+
+	lines = append(lines,
+		fmt.Sprintf("xdp.rx_pps:%g|g|#%s", s.PPS, tags),
+		fmt.Sprintf("xdp.rx_bps:%g|g|#%s", s.BPS, tags))
+
+	for _, line := range lines {
+		if d.batch.Len() > 0 && d.batch.Len()+1+len(line) > statsdMaxPacket {
+			d.send()
+		}
+
+		if d.batch.Len() > 0 {
+			d.batch.WriteByte('\n')
+		}
+
+		d.batch.WriteString(line)
+	}
+
+	return nil
+}
+
+// Flush sends what is left of the tick's batch.
+func (d *statsdSink) Flush() error {
+	if d.batch.Len() > 0 {
+		d.send()
+	}
+
+	return nil
+}
+
+// send sends the batch, logging the first of a run of failures
+func (d *statsdSink) send() {
+	_, err := d.conn.Write(d.batch.Bytes())
+	d.batch.Reset()
+
+	switch {
+	case err != nil && !d.failed:
+		warn(warnStatsdSend, 1)
+		log.Printf("statsd: sending metrics failed, dropping them until it recovers: %v\n", err)
+	case err == nil && d.failed:
+		log.Println("statsd: sending metrics again")
+	}
+
+	d.failed = err != nil
+}
+
+func (d *statsdSink) Close() error {
+	d.Flush()
+
+	return d.conn.Close()
+}
+
+var statsdEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_", " ", "_")
+
+// statsdTags returns the tags of the series of s, always in the same order
+func statsdTags(s Sample) string {
+	tags := "action:" + statsdEscaper.Replace(strings.ToLower(s.Action))
+
+	if s.Label != "" {
+		tags += ",label:" + statsdEscaper.Replace(s.Label)
+	}
+
+	if s.Map != "" {
+		tags += ",map:" + statsdEscaper.Replace(s.Map)
+	}
+
+	return tags
+}
@@ -0,0 +1,78 @@
+package statstest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bxffour/nstats/internal/stats"
+)
+
+// tickingMap moves the clock on by a second each time the last action is
+// looked up, once a read is done
+type tickingMap struct {
+	*Map
+	clock *Clock
+}
+
+func (m tickingMap) Lookup(key, valueOut interface{}) error {
+	if k, ok := key.(*uint32); ok && *k == stats.NumActions-1 {
+		defer m.clock.Advance(time.Second)
+	}
+
+	return m.Map.Lookup(key, valueOut)
+}
+
+// collector keeps the samples of the first action until it has n of them
+type collector struct {
+	n       int
+	cancel  func()
+	samples []stats.Sample
+}
+
+func (c *collector) Write(s stats.Sample) error {
+	if s.Action == stats.Actions()[0].Name && len(c.samples) < c.n {
+		if c.samples = append(c.samples, s); len(c.samples) == c.n {
+			c.cancel()
+		}
+	}
+
+	return nil
+}
+
+func (c *collector) Close() error { return nil }
+
+func TestClockDrivesSession(t *testing.T) {
+	clock := NewClock(time.Unix(1700000000, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sink := &collector{n: 4, cancel: cancel}
+	opts := stats.Options{
+		Clock:              clock,
+		Interval:           time.Millisecond,
+		RateWindow:         2 * time.Second,
+		RelativeTimestamps: true,
+		Sinks:              []stats.OutputSink{sink},
+	}
+
+	m := tickingMap{NewMap(stats.NumActions, 1), clock}
+	if err := stats.Stream(ctx, m, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the baseline is read at the start, a second before the first tick
+	for i, s := range sink.samples {
+		if want := float64(i + 1); s.Elapsed != want {
+			t.Errorf("tick %d: elapsed %vs, want %vs", i+1, s.Elapsed, want)
+		}
+
+		// the window holds the reads of the two seconds before the tick as
+		// told by the clock, the first tick being taken over the baseline
+		want := []float64{1, 1, 2, 2}[i]
+		if s.Period != want {
+			t.Errorf("tick %d: period %vs, want %vs", i+1, s.Period, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package statstest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bxffour/nstats/internal/stats"
+)
+
+// counter counts the samples written to it
+type counter struct{ n int }
+
+func (c *counter) Write(stats.Sample) error { c.n++; return nil }
+func (c *counter) Close() error             { return nil }
+
+func TestCountEmitWindows(t *testing.T) {
+	clock := NewClock(time.Unix(1700000000, 0))
+	sink := &counter{}
+
+	opts := stats.Options{
+		Clock:        clock,
+		Interval:     time.Millisecond,
+		EmitInterval: 3 * time.Second,
+		Count:        2,
+		Sinks:        []stats.OutputSink{sink},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	m := tickingMap{NewMap(stats.NumActions, 1), clock}
+	if err := stats.Stream(ctx, m, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("Stream ran until cancelled rather than ending after Count windows")
+	}
+
+	if want := 2 * stats.NumActions; sink.n != want {
+		t.Errorf("%d samples written, want %d, a window of every action twice", sink.n, want)
+	}
+
+	// the first tick opens the windows, and each closes three ticks on
+	if now, want := clock.Now(), time.Unix(1700000000, 0).Add(8*time.Second); !now.Equal(want) {
+		t.Errorf("Stream read the map until %v, want %v", now, want)
+	}
+}
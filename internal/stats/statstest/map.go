@@ -0,0 +1,140 @@
+// Package statstest provides an in-memory stats map so the collection
+// pipeline can be exercised without loading eBPF objects or running as root.
+package statstest
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/cilium/ebpf"
+)
+
+// Value is the per-CPU value stored for a key, matching struct datarec in
+// xdp/kern.c.
+type Value struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// Map is a fake per-CPU array map keyed by XDP action. It satisfies
+// stats.MapLooker.
+type Map struct {
+	mu      sync.Mutex
+	values  map[uint32][]Value
+	cpus    int
+	lookups int
+
+	// counters32 encodes the values with 32-bit counters
+	counters32 bool
+}
+
+// NewMap returns a map holding entries zeroed for cpus CPUs for each of the
+// given keys.
+func NewMap(entries uint32, cpus int) *Map {
+	m := &Map{values: make(map[uint32][]Value, entries), cpus: cpus}
+
+	for key := uint32(0); key < entries; key++ {
+		m.values[key] = make([]Value, cpus)
+	}
+
+	return m
+}
+
+// NewMap32 is NewMap for programs keeping 32-bit counters, as in maps with
+// a value size of 8. Counters wrap around at 2^32.
+func NewMap32(entries uint32, cpus int) *Map {
+	m := NewMap(entries, cpus)
+	m.counters32 = true
+
+	return m
+}
+
+// Set replaces the per-CPU values of key.
+func (m *Map) Set(key uint32, perCPU ...Value) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = append([]Value(nil), perCPU...)
+}
+
+// Add simulates packets totalling bytes hitting key on the given CPU.
+func (m *Map) Add(key uint32, cpu int, packets, bytes uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v := m.values[key]
+	for len(v) <= cpu {
+		v = append(v, Value{})
+	}
+
+	v[cpu].Packets += packets
+	v[cpu].Bytes += bytes
+	m.values[key] = v
+}
+
+// Lookups returns how many times Lookup has been called.
+func (m *Map) Lookups() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lookups
+}
+
+// Lookup decodes the per-CPU values of key into valueOut, which must be a
+// pointer to a slice whose elements implement encoding.BinaryUnmarshaler, the
+// same way the kernel values are handed to them by *ebpf.Map.
+func (m *Map) Lookup(key, valueOut interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lookups++
+
+	var k uint32
+	switch key := key.(type) {
+	case uint32:
+		k = key
+	case *uint32:
+		k = *key
+	default:
+		return fmt.Errorf("statstest: unsupported key type %T", key)
+	}
+
+	values, ok := m.values[k]
+	if !ok {
+		return fmt.Errorf("lookup key %d: %w", k, ebpf.ErrKeyNotExist)
+	}
+
+	out := reflect.ValueOf(valueOut)
+	if out.Kind() != reflect.Pointer || out.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("statstest: valueOut must be a pointer to a slice, got %T", valueOut)
+	}
+
+	slice := reflect.MakeSlice(out.Elem().Type(), len(values), len(values))
+	for i, v := range values {
+		u, ok := slice.Index(i).Addr().Interface().(encoding.BinaryUnmarshaler)
+		if !ok {
+			return fmt.Errorf("statstest: %s does not implement encoding.BinaryUnmarshaler", slice.Index(i).Type())
+		}
+
+		buf := make([]byte, 16)
+		binary.LittleEndian.PutUint64(buf[0:], v.Packets)
+		binary.LittleEndian.PutUint64(buf[8:], v.Bytes)
+
+		if m.counters32 {
+			buf = make([]byte, 8)
+			binary.LittleEndian.PutUint32(buf[0:], uint32(v.Packets))
+			binary.LittleEndian.PutUint32(buf[4:], uint32(v.Bytes))
+		}
+
+		if err := u.UnmarshalBinary(buf); err != nil {
+			return err
+		}
+	}
+
+	out.Elem().Set(slice)
+
+	return nil
+}
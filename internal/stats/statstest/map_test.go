@@ -0,0 +1,72 @@
+package statstest
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf"
+)
+
+// raw records the bytes a value is decoded from
+type raw []byte
+
+func (r *raw) UnmarshalBinary(p []byte) error {
+	*r = append(raw(nil), p...)
+	return nil
+}
+
+func TestMapLookup(t *testing.T) {
+	m := NewMap(2, 2)
+	m.Add(1, 1, 3, 300)
+	m.Add(1, 1, 2, 200)
+
+	var values []raw
+	if err := m.Lookup(uint32(1), &values); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 2 {
+		t.Fatalf("got %d values, want one per CPU", len(values))
+	}
+
+	if p, b := binary.LittleEndian.Uint64(values[1]), binary.LittleEndian.Uint64(values[1][8:]); p != 5 || b != 500 {
+		t.Errorf("CPU 1 holds %d packets, %d bytes, want 5 packets, 500 bytes", p, b)
+	}
+
+	key := uint32(0)
+	if err := m.Lookup(&key, &values); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Lookup(uint32(2), &values); !errors.Is(err, ebpf.ErrKeyNotExist) {
+		t.Errorf("looking up a missing key: %v, want ErrKeyNotExist", err)
+	}
+
+	if err := m.Lookup("1", &values); err == nil {
+		t.Error("looking up a string key succeeded")
+	}
+
+	if n := m.Lookups(); n != 4 {
+		t.Errorf("Lookups = %d, want 4", n)
+	}
+}
+
+func TestMap32Lookup(t *testing.T) {
+	m := NewMap32(1, 1)
+	m.Set(0, Value{1<<32 - 1, 1<<32 + 5})
+	m.Add(0, 0, 2, 0)
+
+	var values []raw
+	if err := m.Lookup(uint32(0), &values); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values[0]) != 8 {
+		t.Fatalf("values are %d bytes, want 8", len(values[0]))
+	}
+
+	if p, b := binary.LittleEndian.Uint32(values[0]), binary.LittleEndian.Uint32(values[0][4:]); p != 1 || b != 5 {
+		t.Errorf("got %d packets, %d bytes, want the counters wrapped to 1 packet, 5 bytes", p, b)
+	}
+}
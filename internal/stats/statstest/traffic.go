@@ -0,0 +1,214 @@
+package statstest
+
+import (
+	"context"
+	"time"
+
+	"github.com/bxffour/nstats/internal/stats"
+)
+
+// Traffic is the packets and bytes hitting each key of a map over one tick.
+type Traffic map[uint32]Value
+
+// Replay streams samples from m with opts, adding ticks[i] to m during tick i
+// so that it is counted by tick i+1, and returns the samples of those ticks.
+// The traffic of a tick is spread over the CPUs of the map in turn.
+//
+// opts.Sinks and opts.Filter are replaced to record every sample, the rate
+// window is dropped so rates cover a single tick, and an unset Interval
+// defaults to a millisecond. lookups[i] is the number of lookups made up to
+// the samples returned for ticks[i], the baseline read included.
+func Replay(ctx context.Context, m *Map, opts stats.Options, ticks []Traffic) (samples [][]stats.Sample, lookups []int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rec := &recorder{m: m, ticks: ticks, cancel: cancel}
+
+	opts.Sinks = []stats.OutputSink{rec}
+	opts.Filter = nil
+	opts.RateWindow = 0
+
+	if opts.Interval == 0 {
+		opts.Interval = time.Millisecond
+	}
+
+	if err := stats.Stream(ctx, m, opts); err != nil {
+		return nil, nil, err
+	}
+
+	if len(rec.samples) < len(ticks)+1 {
+		return nil, nil, ctx.Err()
+	}
+
+	// the first tick only counts what was in the map to begin with
+	return rec.samples[1 : len(ticks)+1], rec.lookups[1 : len(ticks)+1], nil
+}
+
+// TB is the part of testing.TB CheckTraffic reports to.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// CheckTraffic replays ticks through the collection pipeline with opts and
+// fails tb, usually a testing.TB, unless the samples of every tick total the
+// counters the map started with plus the traffic replayed so far, the rates
+// are exactly that tick's traffic over its period, and the map was read once
+// per tick, or twice with opts.StableReads. The counters of maps made by
+// NewMap32 wrap around at 2^32 on each CPU, so their totals are expected as
+// the sum of the wrapped per-CPU counters, while their rates still are the
+// traffic replayed.
+func CheckTraffic(tb TB, m *Map, opts stats.Options, ticks []Traffic) {
+	tb.Helper()
+
+	// the per-CPU counters as replayed, the traffic of a tick going to a
+	// CPU in turn as it does in Replay
+	values := make(map[uint32][]Value, len(m.values))
+
+	m.mu.Lock()
+	for key, perCPU := range m.values {
+		values[key] = append([]Value(nil), perCPU...)
+	}
+	cpus, counters32 := m.cpus, m.counters32
+	m.mu.Unlock()
+
+	if cpus < 1 {
+		cpus = 1
+	}
+
+	total := func(key uint32) Value {
+		var sum Value
+		for _, v := range values[key] {
+			if counters32 {
+				v = Value{uint64(uint32(v.Packets)), uint64(uint32(v.Bytes))}
+			}
+
+			sum = Value{sum.Packets + v.Packets, sum.Bytes + v.Bytes}
+		}
+
+		return sum
+	}
+
+	zero := make(map[uint32]Value, len(values))
+	if opts.ZeroOnStart {
+		for key := range values {
+			zero[key] = total(key)
+		}
+	}
+
+	samples, lookups, err := Replay(context.Background(), m, opts, ticks)
+	if err != nil {
+		tb.Fatalf("replaying traffic: %v", err)
+	}
+
+	reads := 1
+	if opts.StableReads {
+		reads = 2
+	}
+
+	for i, tick := range ticks {
+		// the baseline, the first tick and the ticks since
+		if want := (1 + (i+2)*reads) * stats.NumActions; lookups[i] != want {
+			tb.Errorf("tick %d: %d lookups, expected %d: the map is read more than once per tick", i+1, lookups[i], want)
+		}
+
+		for key, t := range tick {
+			v := values[key]
+			for len(v) <= i%cpus {
+				v = append(v, Value{})
+			}
+
+			v[i%cpus] = Value{v[i%cpus].Packets + t.Packets, v[i%cpus].Bytes + t.Bytes}
+			values[key] = v
+		}
+
+		for key := uint32(0); key < stats.NumActions; key++ {
+			if int(key) >= len(samples[i]) {
+				tb.Fatalf("tick %d: %d samples, expected at least %d", i+1, len(samples[i]), stats.NumActions)
+			}
+
+			s, t := samples[i][key], tick[key]
+
+			want := total(key)
+			want = Value{sinceZero(want.Packets, zero[key].Packets), sinceZero(want.Bytes, zero[key].Bytes)}
+
+			if s.Packets != want.Packets || s.Bytes != want.Bytes {
+				tb.Errorf("tick %d, %s: totals %d packets, %d bytes, expected %d packets, %d bytes",
+					i+1, s.Action, s.Packets, s.Bytes, want.Packets, want.Bytes)
+			}
+
+			pps := float64(t.Packets) / s.Period
+			bps := float64(t.Bytes) * 8 / s.Period
+
+			if s.PPS != pps || s.BPS != bps {
+				tb.Errorf("tick %d, %s: rates %v pps, %v bps over %vs, expected %v pps, %v bps",
+					i+1, s.Action, s.PPS, s.BPS, s.Period, pps, bps)
+			}
+		}
+	}
+}
+
+// sinceZero is the total of a counter since it was at zero, as the samples of
+// stats.Options.ZeroOnStart give it
+func sinceZero(total, zero uint64) uint64 {
+	if total < zero {
+		return total
+	}
+
+	return total - zero
+}
+
+// recorder is the sink of Replay. Sampling is synchronous, so traffic added
+// while a tick is written is only seen by the next one.
+type recorder struct {
+	m      *Map
+	ticks  []Traffic
+	cancel func()
+
+	samples [][]stats.Sample
+	lookups []int
+	first   string
+	done    bool
+}
+
+func (r *recorder) Write(s stats.Sample) error {
+	if r.done {
+		return nil
+	}
+
+	if r.first == "" {
+		r.first = s.Action
+	}
+
+	if s.Action == r.first {
+		// a new tick
+		tick := len(r.samples)
+		if tick > len(r.ticks) {
+			r.done = true
+			r.cancel()
+
+			return nil
+		}
+
+		r.samples = append(r.samples, nil)
+		r.lookups = append(r.lookups, r.m.Lookups())
+
+		if tick < len(r.ticks) {
+			for key, v := range r.ticks[tick] {
+				cpus := r.m.cpus
+				if cpus < 1 {
+					cpus = 1
+				}
+
+				r.m.Add(key, tick%cpus, v.Packets, v.Bytes)
+			}
+		}
+	}
+
+	r.samples[len(r.samples)-1] = append(r.samples[len(r.samples)-1], s)
+
+	return nil
+}
+
+func (r *recorder) Close() error { return nil }
@@ -0,0 +1,55 @@
+package statstest
+
+import (
+	"testing"
+
+	"github.com/bxffour/nstats/internal/stats"
+)
+
+// traffic returns n ticks of traffic, tick i bringing i+1 packets of 100
+// bytes to every action, and more to XDP_PASS
+func traffic(n int) []Traffic {
+	ticks := make([]Traffic, n)
+	for i := range ticks {
+		ticks[i] = Traffic{}
+		for key := uint32(0); key < stats.NumActions; key++ {
+			ticks[i][key] = Value{uint64(i + 1), uint64(i+1) * 100}
+		}
+
+		ticks[i][2] = Value{uint64(i+1) * 1000, uint64(i+1) * 100000}
+	}
+
+	return ticks
+}
+
+func TestCheckTraffic64(t *testing.T) {
+	m := NewMap(stats.NumActions, 4)
+	m.Set(2, Value{1 << 40, 1 << 50}, Value{7, 700})
+
+	CheckTraffic(t, m, stats.Options{}, traffic(6))
+}
+
+func TestCheckTraffic32Wrap(t *testing.T) {
+	m := NewMap32(stats.NumActions, 2)
+
+	// XDP_PASS wraps on the first CPU on the first tick, and its bytes on
+	// the second one a tick later
+	m.Set(2, Value{1<<32 - 500, 1<<32 - 50000}, Value{10, 1<<32 - 150000})
+	m.Set(1, Value{1<<32 - 1, 1<<32 - 1}, Value{})
+
+	CheckTraffic(t, m, stats.Options{}, traffic(6))
+}
+
+func TestCheckTrafficZeroOnStart(t *testing.T) {
+	m := NewMap(stats.NumActions, 2)
+	m.Set(0, Value{100, 10000}, Value{200, 20000})
+
+	CheckTraffic(t, m, stats.Options{ZeroOnStart: true}, traffic(4))
+}
+
+func TestCheckTrafficStableReads(t *testing.T) {
+	m := NewMap(stats.NumActions, 2)
+	m.Set(2, Value{5000, 500000}, Value{5000, 500000})
+
+	CheckTraffic(t, m, stats.Options{StableReads: true, StableReadsTolerance: 0.01}, traffic(4))
+}
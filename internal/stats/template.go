@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// TemplateFields returns the fields of Sample available to output templates.
+func TemplateFields() []string {
+	t := reflect.TypeOf(Sample{})
+	fields := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		fields = append(fields, "."+t.Field(i).Name)
+	}
+
+	return fields
+}
+
+type templateSink struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+// NewTemplateSink returns a sink writing every sample to w formatted by the
+// text/template text, executed with the Sample as its data. A newline is
+// added after each sample unless the template ends with one. Templates using
+// fields Sample doesn't have are rejected here rather than on the first tick.
+func NewTemplateSink(w io.Writer, text string) (OutputSink, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	if err := tmpl.Execute(io.Discard, Sample{}); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	return &templateSink{w: w, tmpl: tmpl}, nil
+}
+
+func (t *templateSink) Write(s Sample) error { return t.tmpl.Execute(t.w, s) }
+func (t *templateSink) Close() error         { return nil }
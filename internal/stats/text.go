@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// textSink writes the samples as the plain text lines of a table, aligned
+// within each tick
+type textSink struct {
+	w      *tabwriter.Writer
+	header bool
+}
+
+// NewTextSink returns a sink writing samples to w as plain text, one line per
+// sample with the columns of the table, aligned within each tick and
+// preceded by a header line once.
+func NewTextSink(w io.Writer) OutputSink {
+	return &textSink{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), header: true}
+}
+
+func (t *textSink) Write(s Sample) error {
+	if t.header {
+		t.header = false
+
+		if _, err := fmt.Fprintln(t.w, "Action\tPackets\tRate\tBytes\tBit rate\tPeriod"); err != nil {
+			return err
+		}
+	}
+
+	action := s.Action
+	if s.Map != "" {
+		action = s.Map + " " + action
+	}
+
+	_, err := fmt.Fprintf(t.w, "%s\t%d\t%s\t%s\t%s\t%s\n", action, s.Packets, strings.TrimSpace(formatPPS(s.PPS)),
+		formatBytes(s.Bytes), strings.TrimSpace(formatRate(s.BPS, "bit")), formatPeriod(s.Period))
+
+	return err
+}
+
+// Flush writes out the aligned lines of the tick.
+func (t *textSink) Flush() error { return t.w.Flush() }
+func (t *textSink) Close() error { return t.w.Flush() }
@@ -0,0 +1,185 @@
+package stats
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ValueSchema decodes the value struct of a stats map. Programs recording
+// more than the packet and byte counters, drop reasons for instance, get a
+// schema of their own, its extra counters shown as columns of their own.
+type ValueSchema struct {
+	Name string
+
+	// Size is the size of the value struct in bytes, the value size of the
+	// maps it decodes
+	Size int
+
+	// Counters names the counters decoded besides packets and bytes
+	Counters []string
+
+	// Packets and Bytes name the packet and byte counters, heading their
+	// columns with Options.ColumnsFromMap. Empty keeps the usual headers.
+	Packets, Bytes string
+
+	// Decode returns the packets, the bytes and the Counters of a value of
+	// Size bytes
+	Decode func(p []byte) (packets, bytes uint64, counters []uint64, err error)
+
+	// Width is the width in bits of the packet and byte counters, 32 for
+	// counters that wrap at 2^32 and are decoded widened; zero is 64
+	Width int
+}
+
+var (
+	valueSchemasMu sync.Mutex
+	valueSchemas   = map[string]ValueSchema{}
+
+	// valueSchemaBySize holds the schema decoding the values of each size
+	valueSchemaBySize = map[int]ValueSchema{}
+)
+
+func init() {
+	RegisterValueSchema(ValueSchema{
+		Name: "datarec",
+		Size: datarecSize,
+		Decode: func(p []byte) (uint64, uint64, []uint64, error) {
+			return binary.LittleEndian.Uint64(p), binary.LittleEndian.Uint64(p[8:]), nil, nil
+		},
+	})
+
+	RegisterValueSchema(ValueSchema{
+		Name:  "datarec32",
+		Size:  datarec32Size,
+		Width: 32,
+		Decode: func(p []byte) (uint64, uint64, []uint64, error) {
+			return uint64(binary.LittleEndian.Uint32(p)), uint64(binary.LittleEndian.Uint32(p[4:])), nil, nil
+		},
+	})
+}
+
+// RegisterValueSchema makes s available to UseValueSchema and decodes the
+// values of s.Size bytes with it unless another schema of that size was
+// registered first.
+func RegisterValueSchema(s ValueSchema) {
+	valueSchemasMu.Lock()
+	defer valueSchemasMu.Unlock()
+
+	valueSchemas[s.Name] = s
+
+	if _, ok := valueSchemaBySize[s.Size]; !ok {
+		valueSchemaBySize[s.Size] = s
+	}
+}
+
+// ParseValueSchema returns the registered schema named spec, or a schema of
+// 64-bit counters named by the comma separated spec, the first two being the
+// packets and the bytes, e.g. "packets,bytes,no_route,acl".
+func ParseValueSchema(spec string) (ValueSchema, error) {
+	valueSchemasMu.Lock()
+	s, ok := valueSchemas[spec]
+	valueSchemasMu.Unlock()
+
+	if ok {
+		return s, nil
+	}
+
+	names := strings.Split(spec, ",")
+	if len(names) < 3 {
+		return ValueSchema{}, fmt.Errorf("unknown value schema %q (known schemas: %s), or expected packets,bytes,counter,...", spec, strings.Join(ValueSchemas(), ", "))
+	}
+
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if names[i] == "" {
+			return ValueSchema{}, fmt.Errorf("invalid value schema %q: empty counter name", spec)
+		}
+	}
+
+	counters := names[2:]
+
+	return ValueSchema{
+		Name:     spec,
+		Size:     8 * len(names),
+		Counters: counters,
+		Packets:  names[0],
+		Bytes:    names[1],
+		Decode: func(p []byte) (uint64, uint64, []uint64, error) {
+			extra := make([]uint64, len(counters))
+			for i := range extra {
+				extra[i] = binary.LittleEndian.Uint64(p[16+8*i:])
+			}
+
+			return binary.LittleEndian.Uint64(p), binary.LittleEndian.Uint64(p[8:]), extra, nil
+		},
+	}, nil
+}
+
+// ValueSchemas returns the names of the registered schemas.
+func ValueSchemas() []string {
+	valueSchemasMu.Lock()
+	defer valueSchemasMu.Unlock()
+
+	names := make([]string, 0, len(valueSchemas))
+	for name := range valueSchemas {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// UseValueSchema decodes the values of s.Size bytes with s from now on,
+// whichever schema of that size was registered. It applies to every map
+// read by the process.
+func UseValueSchema(s ValueSchema) {
+	valueSchemasMu.Lock()
+	defer valueSchemasMu.Unlock()
+
+	valueSchemaBySize[s.Size] = s
+}
+
+// ValueSchemaFor returns the schema decoding values of size bytes, and false
+// if there is none.
+func ValueSchemaFor(size int) (ValueSchema, bool) {
+	valueSchemasMu.Lock()
+	defer valueSchemasMu.Unlock()
+
+	s, ok := valueSchemaBySize[size]
+
+	return s, ok
+}
+
+// counterRate returns the rate of the extra counter name of s since the
+// previous tick, with ColumnsFromMap
+func (o *Options) counterRate(s Sample, name string) (float64, bool) {
+	if o.ColumnsFromMap == nil || o.last == nil || o.warming || s.Period <= 0 {
+		return 0, false
+	}
+
+	prev, ok := o.last.find(s.Action, s.Map)
+	if !ok {
+		return 0, false
+	}
+
+	last, ok := prev.Counters[name]
+	if !ok || s.Counters[name] < last {
+		return 0, false
+	}
+
+	return float64(s.Counters[name]-last) / s.Period, true
+}
+
+// headers returns the headers of the packet and byte columns, named after
+// the fields of the ColumnsFromMap schema if it names them
+func (o *Options) headers() (packets, pps, bytes, bps string) {
+	if s := o.ColumnsFromMap; s != nil && s.Packets != "" && s.Bytes != "" {
+		return s.Packets, s.Packets + "/s", s.Bytes, s.Bytes + " bit/s"
+	}
+
+	return "Total Packets", "Packets Per Sec", "Total Bytes", "Bits Per Sec"
+}
@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// encode returns a value of 64-bit counters
+func encode(counters ...uint64) []byte {
+	p := make([]byte, 8*len(counters))
+	for i, c := range counters {
+		binary.LittleEndian.PutUint64(p[8*i:], c)
+	}
+
+	return p
+}
+
+// decodeSample decodes the values of the CPUs, the counters of each, and
+// returns the sample of their totals
+func decodeSample(t *testing.T, perCPU ...[]uint64) Sample {
+	t.Helper()
+
+	values := make([]datarec, len(perCPU))
+	for i, counters := range perCPU {
+		if err := values[i].UnmarshalBinary(encode(counters...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	at := time.Unix(1700000000, 0)
+
+	return calcStats(records(at), records(at.Add(time.Second), values...))[0]
+}
+
+// useValueSchema is UseValueSchema until the end of the test
+func useValueSchema(t *testing.T, s ValueSchema) {
+	prev, ok := ValueSchemaFor(s.Size)
+	t.Cleanup(func() {
+		valueSchemasMu.Lock()
+		defer valueSchemasMu.Unlock()
+
+		if ok {
+			valueSchemaBySize[s.Size] = prev
+		} else {
+			delete(valueSchemaBySize, s.Size)
+		}
+	})
+
+	UseValueSchema(s)
+}
+
+func TestRegisteredValueSchema(t *testing.T) {
+	RegisterValueSchema(ValueSchema{
+		Name:     "test_reasons",
+		Size:     24,
+		Counters: []string{"no_route"},
+		Decode: func(p []byte) (uint64, uint64, []uint64, error) {
+			return binary.LittleEndian.Uint64(p), binary.LittleEndian.Uint64(p[8:]), []uint64{binary.LittleEndian.Uint64(p[16:])}, nil
+		},
+	})
+
+	t.Cleanup(func() {
+		valueSchemasMu.Lock()
+		defer valueSchemasMu.Unlock()
+
+		delete(valueSchemas, "test_reasons")
+		delete(valueSchemaBySize, 24)
+	})
+
+	s, err := ParseValueSchema("test_reasons")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Size != 24 {
+		t.Fatalf("ParseValueSchema returned %q of %d bytes", s.Name, s.Size)
+	}
+
+	got := decodeSample(t, []uint64{10, 1000, 2}, []uint64{5, 500, 3})
+	if got.Packets != 15 || got.Bytes != 1500 || got.Counters["no_route"] != 5 {
+		t.Errorf("decoded %d packets, %d bytes and counters %v, want 15, 1500 and no_route=5", got.Packets, got.Bytes, got.Counters)
+	}
+}
+
+func TestParsedValueSchema(t *testing.T) {
+	s, err := ParseValueSchema("packets,bytes,reason_a,reason_b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Size != 32 || s.Packets != "packets" || s.Bytes != "bytes" {
+		t.Fatalf("parsed %d bytes, packets %q and bytes %q", s.Size, s.Packets, s.Bytes)
+	}
+
+	info := &ebpf.MapInfo{Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: 32}
+	if err := ValidateMap(info); !errors.Is(err, ErrMapTypeMismatch) {
+		t.Errorf("ValidateMap of 32 byte values without the schema: %v, want ErrMapTypeMismatch", err)
+	}
+
+	useValueSchema(t, s)
+
+	if err := ValidateMap(info); err != nil {
+		t.Errorf("ValidateMap of 32 byte values with the schema: %v", err)
+	}
+
+	got := decodeSample(t, []uint64{10, 1000, 1, 4}, []uint64{5, 500, 2, 3})
+	if got.Packets != 15 || got.Bytes != 1500 {
+		t.Errorf("decoded %d packets and %d bytes, want 15 and 1500", got.Packets, got.Bytes)
+	}
+
+	if got.Counters["reason_a"] != 3 || got.Counters["reason_b"] != 7 || len(got.Counters) != 2 {
+		t.Errorf("counters %v, want reason_a=3 reason_b=7", got.Counters)
+	}
+
+	var buf bytes.Buffer
+	sink := NewCSVSink(&buf)
+	if err := sink.Write(got); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), ",reason_a=3 reason_b=7") {
+		t.Errorf("CSV lacks the counters column:\n%s", buf.String())
+	}
+}
+
+func TestValidateMapValueSizes(t *testing.T) {
+	for size, ok := range map[uint32]bool{datarecSize: true, datarec32Size: true, 12: false, 40: false} {
+		err := ValidateMap(&ebpf.MapInfo{Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: size})
+		if (err == nil) != ok {
+			t.Errorf("ValidateMap of %d byte values: %v", size, err)
+		}
+	}
+}
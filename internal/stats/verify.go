@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"fmt"
+	"log"
+)
+
+// verifyCounters reports the counters of recv lower than in prev, the
+// previous read of the same map. Counters only grow while the program runs,
+// so a decrease points at a misbehaving map or program, or a reset.
+func (o *Options) verifyCounters(src Source, prev, recv StatsRecord) {
+	for i := range recv.Records {
+		var (
+			act  = o.ActionNames.name(uint(i))
+			p, r = prev.record(i).total, recv.Records[i].total
+		)
+
+		if prev.record(i).timestamp.IsZero() {
+			continue
+		}
+
+		if r.rxPackets < p.rxPackets {
+			o.counterViolation(src, act, "packets", p.rxPackets, r.rxPackets)
+		}
+
+		if r.rxBytes < p.rxBytes {
+			o.counterViolation(src, act, "bytes", p.rxBytes, r.rxBytes)
+		}
+	}
+}
+
+// counterViolation logs a counter going backward, or keeps it for the end of
+// the session while the table is up
+func (o *Options) counterViolation(src Source, action, counter string, prev, cur uint64) {
+	msg := fmt.Sprintf("%s %s went backward by %d, from %d to %d", action, counter, prev-cur, prev, cur)
+	if src.Name != "" {
+		msg = src.Name + " " + msg
+	}
+
+	if o.tui {
+		o.violations = append(o.violations, msg)
+		return
+	}
+
+	log.Printf("verify-counters: %s\n", msg)
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// View selects the columns of the table.
+type View string
+
+const (
+	// ViewBoth shows the totals and the rates.
+	ViewBoth View = "both"
+	// ViewRates shows the rates and the period they are taken over.
+	ViewRates View = "rates"
+	// ViewTotals shows the totals.
+	ViewTotals View = "totals"
+)
+
+// ParseView parses a view name, the empty name being ViewBoth.
+func ParseView(name string) (View, error) {
+	switch v := View(name); v {
+	case "":
+		return ViewBoth, nil
+	case ViewBoth, ViewRates, ViewTotals:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid view %q: expected rates, totals or both", name)
+	}
+}
+
+// row picks the cells of the columns in the view, in table order
+func (v View) row(action, packets, pps, bytes, bps, period string) []string {
+	row := []string{action}
+
+	if v != ViewRates {
+		row = append(row, packets)
+	}
+
+	if v != ViewTotals {
+		row = append(row, pps)
+	}
+
+	if v != ViewRates {
+		row = append(row, bytes)
+	}
+
+	if v != ViewTotals {
+		row = append(row, bps, period)
+	}
+
+	return row
+}
+
+// Layout arranges the columns of the table.
+type Layout string
+
+const (
+	// LayoutFlat gives every total and rate a column of its own.
+	LayoutFlat Layout = "flat"
+	// LayoutGrouped shows each total with its rate in one column, e.g.
+	// "1234 (56 pps)".
+	LayoutGrouped Layout = "grouped"
+)
+
+// ParseLayout parses a layout name, the empty name being LayoutFlat.
+func ParseLayout(name string) (Layout, error) {
+	switch l := Layout(name); l {
+	case "":
+		return LayoutFlat, nil
+	case LayoutFlat, LayoutGrouped:
+		return l, nil
+	default:
+		return "", fmt.Errorf("invalid layout %q: expected flat or grouped", name)
+	}
+}
+
+// row picks the cells of the columns shown, stacking each total over its rate
+// in one cell with CellStacked, or following it with LayoutGrouped
+func (o *Options) row(action, packets, pps, bytes, bps, period string) []string {
+	if o.View != ViewBoth && o.View != "" {
+		return o.View.row(action, packets, pps, bytes, bps, period)
+	}
+
+	switch {
+	case o.CellStacked:
+		return []string{action, packets + "\n" + pps, bytes + "\n" + bps, period}
+	case o.Layout == LayoutGrouped:
+		return []string{action, group(packets, pps), group(bytes, bps), period}
+	default:
+		return o.View.row(action, packets, pps, bytes, bps, period)
+	}
+}
+
+// group follows a total with its rate in parentheses
+func group(total, rate string) string {
+	return strings.TrimSpace(total) + " (" + strings.TrimSpace(rate) + ")"
+}
+
+// header returns the header of the table
+func (o *Options) header() []string {
+	packets, pps, bytes, bps := o.headers()
+
+	if o.Layout == LayoutGrouped && !o.CellStacked && (o.View == ViewBoth || o.View == "") {
+		packets, bytes = strings.TrimPrefix(packets, "Total "), strings.TrimPrefix(bytes, "Total ")
+		return []string{"Action", packets + " (Δ/s)", bytes + " (bit/s)", "Period"}
+	}
+
+	return o.row("Action", packets, pps, bytes, bps, "Period")
+}
@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Kinds of the non-fatal issues counted over a session
+const (
+	warnMapFailure = iota
+	warnCounterReset
+	warnSinkDrop
+	warnStatsdSend
+	warnSnapshot
+	warnOTLPExport
+	warnExecFailure
+	warnExecSkipped
+	warnStaleRead
+)
+
+// warningNames are the names of each kind in WarningSummary, singular and
+// plural
+var warningNames = [...][2]string{
+	warnMapFailure:   {"map failure", "map failures"},
+	warnCounterReset: {"counter reset", "counter resets"},
+	warnSinkDrop:     {"sample dropped by a sink", "samples dropped by sinks"},
+	warnStatsdSend:   {"statsd send failure", "statsd send failures"},
+	warnSnapshot:     {"snapshot failure", "snapshot failures"},
+	warnOTLPExport:   {"otlp export failure", "otlp export failures"},
+	warnExecFailure:  {"exec command failure", "exec command failures"},
+	warnExecSkipped:  {"tick skipped by exec", "ticks skipped by exec"},
+	warnStaleRead:    {"stale read", "stale reads"},
+}
+
+var (
+	warningsMu sync.Mutex
+	warnings   [len(warningNames)]int
+)
+
+// warn counts n issues of a kind
+func warn(kind, n int) {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+
+	warnings[kind] += n
+}
+
+// Warnings returns the number of non-fatal issues of each kind the process ran
+// into so far, such as maps failing to read or counters going backward, keyed
+// by the name of the kind.
+func Warnings() map[string]int {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+
+	counts := make(map[string]int)
+	for kind, n := range warnings {
+		if n > 0 {
+			counts[warningNames[kind][0]] = n
+		}
+	}
+
+	return counts
+}
+
+// WarningSummary sums up the warnings in one line, e.g. "3 map failures, 1
+// counter reset", or returns an empty string if there were none.
+func WarningSummary() string {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+
+	var parts []string
+
+	for kind, n := range warnings {
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+warningNames[kind][0])
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", n, warningNames[kind][1]))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
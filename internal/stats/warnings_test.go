@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingMap holds packets on one CPU for every action, growing by grow on
+// every read of the first action, and failing every read once failing is set
+type countingMap struct {
+	mu      sync.Mutex
+	packets uint64
+	grow    uint64
+	failing bool
+}
+
+func (m *countingMap) set(packets uint64, failing bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.packets, m.failing = packets, failing
+}
+
+func (m *countingMap) Lookup(key, valueOut interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failing {
+		return errors.New("map gone")
+	}
+
+	if *key.(*uint32) == 0 {
+		m.packets += m.grow
+	}
+
+	*valueOut.(*[]datarec) = []datarec{{rxPackets: m.packets, rxBytes: 100 * m.packets, schema: "datarec"}}
+
+	return nil
+}
+
+// resetWarnings zeroes the warnings counted so far
+func resetWarnings() {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+
+	warnings = [len(warningNames)]int{}
+}
+
+func TestWarnCounterReset(t *testing.T) {
+	resetWarnings()
+
+	var (
+		o    = &Options{}
+		m    = &countingMap{}
+		last StatsRecord
+	)
+
+	// a reset between the second and third reads, counted once for all the
+	// actions
+	for _, packets := range []uint64{1000, 2000, 10, 20} {
+		m.set(packets, false)
+
+		recv, _, err := o.sample(m, last, last, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last = recv
+	}
+
+	if got := Warnings()["counter reset"]; got != 1 {
+		t.Errorf("%d counter resets, want 1", got)
+	}
+}
+
+func TestWarnUnstableRead(t *testing.T) {
+	resetWarnings()
+
+	// the two reads of a tick disagree, the second growing twice as much
+	o := &Options{StableReads: true}
+	m := &countingMap{grow: 1}
+
+	if _, _, err := o.sample(m, StatsRecord{}, StatsRecord{}, nil); !errors.Is(err, errUnstableRead) {
+		t.Fatalf("sample: %v, want errUnstableRead", err)
+	}
+
+	if got := Warnings()["stale read"]; got != 1 {
+		t.Errorf("%d stale reads, want 1", got)
+	}
+}
+
+func TestWarnStaleRead(t *testing.T) {
+	resetWarnings()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	m := &countingMap{}
+	opts := Options{
+		Interval:   time.Millisecond,
+		StaleAfter: time.Hour,
+		Sinks:      []OutputSink{failAfter{m}},
+	}
+
+	if _, _, err := RenderWithEvents(ctx, m, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Warnings()["stale read"]; got == 0 {
+		t.Error("no stale reads counted with the map failing")
+	}
+}
+
+// failAfter makes the map fail once the first tick is written
+type failAfter struct{ m *countingMap }
+
+func (f failAfter) Write(Sample) error {
+	f.m.set(0, true)
+	return nil
+}
+
+func (f failAfter) Close() error { return nil }
@@ -0,0 +1,97 @@
+// Package web serves a small browser dashboard of the samples kept in a
+// stats.History.
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bxffour/nstats/internal/stats"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// Handler returns the dashboard handler. It serves the page at / and the
+// snapshots held by history, oldest first, at /history.json. The number of
+// snapshots returned can be limited with ?n=. The totals of the latest
+// snapshot, without rates, are served at /raw.
+func Handler(history *stats.History) http.Handler {
+	static, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/history.json", func(w http.ResponseWriter, r *http.Request) {
+		n := stats.DefaultHistorySize
+
+		if q := r.URL.Query().Get("n"); q != "" {
+			v, err := strconv.Atoi(q)
+			if err != nil || v < 0 {
+				http.Error(w, "invalid n", http.StatusBadRequest)
+				return
+			}
+
+			n = v
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(history.Last(n))
+	})
+
+	mux.HandleFunc("/raw", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		last := history.Last(1)
+		if len(last) == 0 {
+			http.Error(w, "no samples yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newRaw(last[0]))
+	})
+
+	return mux
+}
+
+// raw holds the cumulative totals of every action at one point in time
+type raw struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Actions   []rawTotals `json:"actions"`
+}
+
+type rawTotals struct {
+	Action  string    `json:"action"`
+	Map     string    `json:"map,omitempty"`
+	Packets uint64    `json:"packets"`
+	Bytes   uint64    `json:"bytes"`
+	Read    time.Time `json:"read"`
+}
+
+// newRaw returns the totals of the samples of snap, groups included
+func newRaw(snap stats.Snapshot) raw {
+	r := raw{Timestamp: snap.Timestamp, Actions: make([]rawTotals, 0, len(snap.Samples))}
+
+	for _, s := range snap.Samples {
+		r.Actions = append(r.Actions, rawTotals{
+			Action:  s.Action,
+			Map:     s.Map,
+			Packets: s.Packets,
+			Bytes:   s.Bytes,
+			Read:    s.Timestamp,
+		})
+	}
+
+	return r
+}
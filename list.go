@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bxffour/nstats/internal/stats"
+	"github.com/cilium/ebpf"
+	"github.com/urfave/cli/v2"
+)
+
+var listCommand = cli.Command{
+	Name:  "list",
+	Usage: "list the maps pinned under a bpffs directory, telling which have the layout of a stats map",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "dir",
+			Value: "/sys/fs/bpf",
+			Usage: "bpffs directory to look for pinned maps under",
+		},
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "also list the maps loaded but not pinned, by ID",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "text for a table, or json for one object per map",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		format := ctx.String("format")
+		if format != "json" && format != "text" {
+			return fmt.Errorf("invalid format %q: expected json or text", format)
+		}
+
+		maps, err := listMaps(ctx.String("dir"), ctx.Bool("all"))
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			for _, m := range maps {
+				if err := enc.Encode(m); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tTYPE\tKEY\tVALUE\tENTRIES\tSTATS\tPIN")
+
+		for _, m := range maps {
+			match := "yes"
+			if !m.Stats {
+				match = "no: " + m.Mismatch
+			}
+
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n", m.ID, m.Name, m.Type, m.KeySize, m.ValueSize, m.MaxEntries, match, m.Pin)
+		}
+
+		return tw.Flush()
+	},
+}
+
+// listedMap describes a map found by the list command
+type listedMap struct {
+	ID         ebpf.MapID `json:"id"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	KeySize    uint32     `json:"key_size"`
+	ValueSize  uint32     `json:"value_size"`
+	MaxEntries uint32     `json:"max_entries"`
+
+	// Stats is set for maps with the layout of a stats map, Mismatch telling
+	// why the others don't
+	Stats    bool   `json:"stats"`
+	Mismatch string `json:"mismatch,omitempty"`
+
+	Pin string `json:"pin,omitempty"`
+}
+
+func describeMap(m *ebpf.Map, pin string) (listedMap, error) {
+	info, err := m.Info()
+	if err != nil {
+		return listedMap{}, err
+	}
+
+	id, _ := info.ID()
+
+	l := listedMap{
+		ID:         id,
+		Name:       info.Name,
+		Type:       info.Type.String(),
+		KeySize:    info.KeySize,
+		ValueSize:  info.ValueSize,
+		MaxEntries: info.MaxEntries,
+		Stats:      true,
+		Pin:        pin,
+	}
+
+	if err := stats.ValidateMap(info); err != nil {
+		l.Stats = false
+		l.Mismatch = strings.TrimPrefix(err.Error(), stats.ErrMapTypeMismatch.Error()+": ")
+	}
+
+	return l, nil
+}
+
+// listMaps returns the maps pinned under dir, and with all the maps loaded
+// without a pin after them. Pins of programs and links are left out.
+func listMaps(dir string, all bool) ([]listedMap, error) {
+	var (
+		maps   []listedMap
+		pinned = make(map[ebpf.MapID]bool)
+	)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		m, err := ebpf.LoadPinnedMap(p, &ebpf.LoadPinOptions{ReadOnly: true})
+		if errors.Is(err, fs.ErrPermission) {
+			return err
+		}
+
+		if err != nil {
+			// not a map
+			return nil
+		}
+		defer m.Close()
+
+		l, err := describeMap(m, p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+
+		pinned[l.ID] = true
+		maps = append(maps, l)
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return nil, fmt.Errorf("%w (listing BPF maps needs root or CAP_BPF)", err)
+	case err != nil:
+		return nil, err
+	}
+
+	if !all {
+		return maps, nil
+	}
+
+	for id := ebpf.MapID(0); ; {
+		next, err := ebpf.MapGetNextID(id)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error listing map IDs: %w", err)
+		}
+
+		id = next
+		if pinned[id] {
+			continue
+		}
+
+		m, err := ebpf.NewMapFromID(id)
+		if err != nil {
+			// the map went away since it was listed
+			continue
+		}
+
+		l, err := describeMap(m, "")
+		m.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("map %d: %w", id, err)
+		}
+
+		maps = append(maps, l)
+	}
+
+	return maps, nil
+}
@@ -15,20 +15,31 @@ import (
 
 var pinPath = "/sys/fs/bpf/xdp_stats"
 
+var pinPathFlag = cli.StringFlag{
+	Name:        "pin-path",
+	Value:       pinPath,
+	Destination: &pinPath,
+	EnvVars:     []string{"NSTATS_PIN_PATH"},
+	Usage:       "bpffs directory the stats map is pinned in",
+}
+
 var startCommand = cli.Command{
 	Name: "load",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:     "dev",
 			Required: true,
+			EnvVars:  []string{"NSTATS_DEV"},
 			Usage:    "<ifname> of interface to attach program to",
 		},
 		&cli.StringFlag{
 			Name:    "sec",
 			Aliases: []string{"S"},
 			Value:   "xdp.pass",
+			EnvVars: []string{"NSTATS_SEC"},
 			Usage:   "choose what section to load. (xdp.pass|xdp.drop|xdp.aborted)",
 		},
+		&pinPathFlag,
 	},
 	Before: func(ctx *cli.Context) error {
 		if err := os.MkdirAll(pinPath, os.ModePerm); err != nil {
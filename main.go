@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/urfave/cli/v2"
+
+	"github.com/bxffour/nstats/internal/stats"
 )
 
 //go:generate go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang bpf ./xdp/kern.c
@@ -12,6 +15,33 @@ import (
 func main() {
 	app := cli.NewApp()
 	app.Name = "xdpstats"
+	app.Description = "Options marked with [$NSTATS_...] can also be set from the environment.\n" +
+		"A flag given on the command line overrides the environment, which overrides the default."
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "color",
+			Value:   "auto",
+			EnvVars: []string{"NSTATS_COLOR"},
+			Usage:   "when to color output (auto|always|never), auto honoring NO_COLOR",
+		},
+		&cli.BoolFlag{
+			Name:    "no-color",
+			EnvVars: []string{"NSTATS_NO_COLOR"},
+			Usage:   "never color output, as --color never",
+		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			EnvVars: []string{"NSTATS_QUIET"},
+			Usage:   "don't print the summary of the warnings of the session to stderr on exit",
+		},
+	}
+	app.After = func(ctx *cli.Context) error {
+		if summary := stats.WarningSummary(); summary != "" && !ctx.Bool("quiet") {
+			fmt.Fprintf(os.Stderr, "warnings: %s\n", summary)
+		}
+
+		return nil
+	}
 	app.Commands = []*cli.Command{
 		&startCommand,
 		&statsCommand,
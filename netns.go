@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/bxffour/nstats/internal/stats"
+	"golang.org/x/sys/unix"
+)
+
+// netnsDir is where iproute2 keeps the named network namespaces
+const netnsDir = "/run/netns"
+
+// inNetns runs fn in the network namespace ns, given by its iproute2 name or
+// as a path such as /proc/<pid>/ns/net. fn runs on a thread of its own, so
+// the rest of the process stays in its namespace.
+func inNetns(ns string, fn func() error) error {
+	p := ns
+	if !strings.Contains(ns, "/") {
+		p = filepath.Join(netnsDir, ns)
+	}
+
+	target, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	done := make(chan error, 1)
+
+	go func() {
+		// a thread that can't be brought back is left locked, so it exits with
+		// the goroutine instead of running others in the wrong namespace
+		runtime.LockOSThread()
+
+		orig, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- err
+			return
+		}
+		defer orig.Close()
+
+		if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			done <- fmt.Errorf("entering network namespace %s: %w", ns, err)
+			return
+		}
+
+		fnErr := fn()
+
+		if err := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET); err != nil {
+			done <- fmt.Errorf("leaving network namespace %s: %w", ns, err)
+			return
+		}
+
+		runtime.UnlockOSThread()
+		done <- fnErr
+	}()
+
+	return <-done
+}
+
+// openNetnsMaps is openStatsMaps run in each of the network namespaces,
+// the maps being named after the namespace they were opened in. The
+// namespaces failing are reported and skipped.
+func openNetnsMaps(namespaces []string, scanDir string, names stats.ActionNames, readOnly bool) ([]*statsMap, error) {
+	var maps []*statsMap
+
+	for _, ns := range namespaces {
+		var nsMaps []*statsMap
+
+		err := inNetns(ns, func() (err error) {
+			nsMaps, err = openStatsMaps(scanDir, names, readOnly)
+			return err
+		})
+		if err != nil {
+			log.Printf("skipping network namespace %s: %v\n", ns, err)
+			continue
+		}
+
+		for _, m := range nsMaps {
+			if m.name == "" {
+				m.name = ns
+			} else {
+				m.name = ns + "/" + m.name
+			}
+		}
+
+		maps = append(maps, nsMaps...)
+	}
+
+	if len(maps) == 0 {
+		return nil, fmt.Errorf("no stats maps found in network namespaces %s", strings.Join(namespaces, ", "))
+	}
+
+	return maps, nil
+}
@@ -1,61 +1,1761 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/bxffour/nstats/internal/journald"
 	"github.com/bxffour/nstats/internal/stats"
+	"github.com/bxffour/nstats/internal/web"
 	"github.com/cilium/ebpf"
 	"github.com/urfave/cli/v2"
 )
 
 var statsCommand = cli.Command{
 	Name: "stats",
+	Subcommands: []*cli.Command{
+		&resetCommand,
+		&benchCommand,
+		&sampleCommand,
+		&ringDumpCommand,
+		&listCommand,
+		&captureCommand,
+	},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "verbose",
+			Usage:   "print extra information",
+			Aliases: []string{"v"},
+		},
+		&pinPathFlag,
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Value:   "table",
+			EnvVars: []string{"NSTATS_OUTPUT"},
+			Usage:   "output format (table|json|csv|template), template being implied by --template",
+		},
+		&cli.StringFlag{
+			Name:    "template",
+			EnvVars: []string{"NSTATS_TEMPLATE"},
+			Usage: "write each sample as the Go text/template <text>, e.g. \"{{.Action}} {{.PPS}}\" (fields: " +
+				strings.Join(stats.TemplateFields(), ", ") + ")",
+		},
+		&cli.StringFlag{
+			Name:    "action-names",
+			EnvVars: []string{"NSTATS_ACTION_NAMES"},
+			Usage:   "label the actions, as key=label pairs (0=DENY,1=ALLOW) or a file of one pair per line",
+		},
+		&cli.StringSliceFlag{
+			Name:    "group-actions",
+			EnvVars: []string{"NSTATS_GROUP_ACTIONS"},
+			Usage:   "add a row summing several actions, e.g. handled=pass,tx,redirect (repeatable, or comma separated)",
+		},
+		&cli.BoolFlag{
+			Name:    "total",
+			Value:   true,
+			EnvVars: []string{"NSTATS_TOTAL"},
+			Usage:   "add a TOTAL row summing every action, also written to the sinks, --total=false leaves it out",
+		},
+		&cli.StringFlag{
+			Name:    "view",
+			Value:   string(stats.ViewBoth),
+			EnvVars: []string{"NSTATS_VIEW"},
+			Usage:   "columns of the table (rates|totals|both)",
+		},
+		&cli.BoolFlag{
+			Name:    "no-separator",
+			EnvVars: []string{"NSTATS_NO_SEPARATOR"},
+			Usage:   "leave out the lines between the rows of the table, fitting more rows",
+		},
+		&cli.BoolFlag{
+			Name:    "no-fill",
+			EnvVars: []string{"NSTATS_NO_FILL"},
+			Usage:   "don't fill the background of the table rows",
+		},
+		&cli.BoolFlag{
+			Name:    "cell-stacked",
+			EnvVars: []string{"NSTATS_CELL_STACKED"},
+			Usage:   "stack each total over its rate in one cell, for narrow terminals",
+		},
+		&cli.StringFlag{
+			Name:    "layout",
+			Value:   string(stats.LayoutFlat),
+			EnvVars: []string{"NSTATS_LAYOUT"},
+			Usage:   "arrange the columns (flat|grouped), grouped following each total with its rate in one column",
+		},
+		&cli.StringFlag{
+			Name:    "rank-by",
+			EnvVars: []string{"NSTATS_RANK_BY"},
+			Usage:   "order the rows by a field, highest first (packets, pps, bytes, bps, period)",
+		},
+		&cli.IntFlag{
+			Name:    "limit",
+			EnvVars: []string{"NSTATS_LIMIT"},
+			Usage:   "with --rank-by, only show the top <n> rows",
+		},
+		&cli.DurationFlag{
+			Name:    "stale-after",
+			EnvVars: []string{"NSTATS_STALE_AFTER"},
+			Usage:   "keep the table up when map reads fail, dimming it once the samples are older than <duration>",
+		},
+		&cli.BoolFlag{
+			Name:    "zero-on-start",
+			EnvVars: []string{"NSTATS_ZERO_ON_START"},
+			Usage:   "count the totals from when the session starts, leaving the kernel counters alone",
+		},
+		&cli.BoolFlag{
+			Name:    "trend",
+			EnvVars: []string{"NSTATS_TREND"},
+			Usage:   "mark the packet rates with ▲, ▼ or ▬ as they rise, fall or hold since the last tick",
+		},
+		&cli.StringFlag{
+			Name:    "action-order",
+			EnvVars: []string{"NSTATS_ACTION_ORDER"},
+			Usage:   "order of the actions in the table, naming each once, e.g. drop,pass,abort,tx,redirect",
+		},
+		&cli.BoolFlag{
+			Name:    "compact-numbers",
+			EnvVars: []string{"NSTATS_COMPACT_NUMBERS"},
+			Usage:   "show packet and byte totals with SI suffixes (1.2M, 3.4G)",
+		},
+		&cli.StringFlag{
+			Name:    "notation",
+			Value:   string(stats.NotationSI),
+			EnvVars: []string{"NSTATS_NOTATION"},
+			Usage:   "write the totals and rates with SI units (si) or in scientific notation like 1.23e9 (scientific), with --sig-figs digits",
+		},
+		&cli.StringFlag{
+			Name:    "filter",
+			Aliases: []string{"expr"},
+			EnvVars: []string{"NSTATS_FILTER"},
+			Usage:   "only output samples matching an expression, e.g. \"pps>100 AND bytes>0\" (fields: packets, pps, bytes, bps, period)",
+		},
+		&cli.StringFlag{
+			Name:    "label",
+			EnvVars: []string{"NSTATS_LABEL"},
+			Usage:   "free-form label added to every sample and the table title",
+		},
+		&cli.IntFlag{
+			Name:    "max-width",
+			EnvVars: []string{"NSTATS_MAX_WIDTH"},
+			Usage:   "cap the table width in columns (0 uses the full terminal)",
+		},
+		&cli.BoolFlag{
+			Name:    "freeze-columns",
+			EnvVars: []string{"NSTATS_FREEZE_COLUMNS"},
+			Usage:   "pad the cells to the widest each column has been, so the numbers don't shift as their digits change",
+		},
+		&cli.IntFlag{
+			Name:    "column-width",
+			EnvVars: []string{"NSTATS_COLUMN_WIDTH"},
+			Usage:   "pad the cells to at least <n> characters, implying --freeze-columns (0 for no minimum)",
+		},
+		&cli.BoolFlag{
+			Name:    "extended-stats",
+			EnvVars: []string{"NSTATS_EXTENDED_STATS"},
+			Usage:   "show sparklines of the drop percentage and of the bit rate of all actions below the table",
+		},
+		&cli.BoolFlag{
+			Name:    "graph",
+			EnvVars: []string{"NSTATS_GRAPH"},
+			Usage:   "show sparklines of the packet rates of the last --graph-history ticks above the table",
+		},
+		&cli.StringFlag{
+			Name:    "graph-actions",
+			Value:   "all",
+			EnvVars: []string{"NSTATS_GRAPH_ACTIONS"},
+			Usage:   "actions to graph with --graph, e.g. drop,pass, or all of them stacked",
+		},
+		&cli.IntFlag{
+			Name:    "graph-history",
+			Value:   stats.DefaultHistorySize,
+			EnvVars: []string{"NSTATS_GRAPH_HISTORY"},
+			Usage:   "number of ticks graphed with --graph, the oldest being dropped",
+		},
+		&cli.IntFlag{
+			Name:    "map-fd",
+			EnvVars: []string{"NSTATS_MAP_FD"},
+			Usage:   "read the stats map behind the inherited file descriptor <n> rather than a pin, e.g. one passed down by a privileged helper",
+		},
+		&cli.UintFlag{
+			Name:    "map-id",
+			EnvVars: []string{"NSTATS_MAP_ID"},
+			Usage:   "read the stats map of BPF map ID <id>, as listed by the list command, rather than a pin",
+		},
+		&cli.StringFlag{
+			Name:    "map-name",
+			EnvVars: []string{"NSTATS_MAP_NAME"},
+			Usage:   "read the loaded stats map named <name>, pinned or not, rather than the pin under --pin-path",
+		},
+		&cli.BoolFlag{
+			Name:    "mock",
+			EnvVars: []string{"NSTATS_MOCK"},
+			Usage:   "show synthetic traffic instead of reading a map, to demo the table without eBPF",
+		},
+		&cli.Float64Flag{
+			Name:    "mock-rate",
+			Value:   100000,
+			EnvVars: []string{"NSTATS_MOCK_RATE"},
+			Usage:   "packets per second of the --mock traffic, on average across the actions",
+		},
+		&cli.BoolFlag{
+			Name:    "compact-header",
+			EnvVars: []string{"NSTATS_COMPACT_HEADER"},
+			Usage:   "show the map name and ID, the interval and the uptime in a single line above the table",
+		},
+		&cli.BoolFlag{
+			Name:    "efficiency",
+			EnvVars: []string{"NSTATS_EFFICIENCY"},
+			Usage:   "show a gauge of the bytes passed as a share of the bytes passed and dropped, this tick and since the start",
+		},
+		&cli.StringFlag{
+			Name:    "iface",
+			Aliases: []string{"interface-stats"},
+			EnvVars: []string{"NSTATS_IFACE"},
+			Usage:   "compare the packets passed to the stack since the start with the receive counters of <ifname> in /proc/net/dev",
+		},
+		&cli.BoolFlag{
+			Name:    "timestamps-relative",
+			EnvVars: []string{"NSTATS_TIMESTAMPS_RELATIVE"},
+			Usage:   "add the seconds since the session started to every sample",
+		},
+		&cli.BoolFlag{
+			Name:    "stable-reads",
+			EnvVars: []string{"NSTATS_STABLE_READS"},
+			Usage:   "read the map twice per tick and discard ticks whose reads disagree (doubles the lookups)",
+		},
+		&cli.Float64Flag{
+			Name:    "stable-reads-tolerance",
+			Value:   0.001,
+			EnvVars: []string{"NSTATS_STABLE_READS_TOLERANCE"},
+			Usage:   "largest relative difference between the growths of the counters since the previous tick seen by the two reads of --stable-reads",
+		},
+		&cli.StringFlag{
+			Name:    "snapshot-dir",
+			Value:   ".",
+			EnvVars: []string{"NSTATS_SNAPSHOT_DIR"},
+			Usage:   "directory the 's' key and --snapshot-every write JSON snapshots to",
+		},
+		&cli.DurationFlag{
+			Name:    "snapshot-every",
+			EnvVars: []string{"NSTATS_SNAPSHOT_EVERY"},
+			Usage:   "write a snapshot to --snapshot-dir every <duration>, e.g. 1m",
+		},
+		&cli.IntFlag{
+			Name:    "snapshot-keep",
+			EnvVars: []string{"NSTATS_SNAPSHOT_KEEP"},
+			Usage:   "keep only the newest <n> snapshots in --snapshot-dir, 0 for all",
+		},
+		&cli.StringFlag{
+			Name:    "web",
+			EnvVars: []string{"NSTATS_WEB"},
+			Usage:   "serve a live dashboard on <addr>, e.g. :8080 or unix:/run/nstats.sock",
+		},
+		&cli.DurationFlag{
+			Name:    "rate-window",
+			EnvVars: []string{"NSTATS_RATE_WINDOW"},
+			Usage:   "average the rates over the last <duration>, e.g. 5s, instead of the last tick",
+		},
+		&cli.StringFlag{
+			Name:    "cpus",
+			EnvVars: []string{"NSTATS_CPUS"},
+			Usage:   "only sum the counters of these cpus, e.g. 0-7,12 (default: all cpus)",
+		},
+		&cli.IntFlag{
+			Name:    "render-fps",
+			Value:   30,
+			EnvVars: []string{"NSTATS_RENDER_FPS"},
+			Usage:   "repaint the table at most <n> times a second, 0 for no limit",
+		},
+		&cli.DurationFlag{
+			Name:    "interval",
+			Value:   stats.DefaultInterval,
+			EnvVars: []string{"NSTATS_INTERVAL"},
+			Usage:   "time between two samples, e.g. 500ms (exclusive with --hz)",
+		},
+		&cli.Float64Flag{
+			Name:    "hz",
+			EnvVars: []string{"NSTATS_HZ"},
+			Usage:   "samples per second, e.g. 2 for --interval 500ms (exclusive with --interval)",
+		},
+		&cli.DurationFlag{
+			Name:    "interval-jitter",
+			EnvVars: []string{"NSTATS_INTERVAL_JITTER"},
+			Usage:   "move every tick by a random amount of up to <duration> either way, e.g. 100ms",
+		},
+		&cli.StringFlag{
+			Name:    "clock",
+			Value:   "monotonic",
+			EnvVars: []string{"NSTATS_CLOCK"},
+			Usage:   "clock the periods of the rates are measured with: monotonic, steady across wall clock steps, or wall",
+		},
+		&cli.BoolFlag{
+			Name:    "once",
+			EnvVars: []string{"NSTATS_ONCE"},
+			Usage:   "print one sample of every action, taken over one interval, as plain lines and exit (as --count 1)",
+		},
+		&cli.IntFlag{
+			Name:    "count",
+			EnvVars: []string{"NSTATS_COUNT"},
+			Usage:   "print <n> samples of every action, one per interval, or per --emit-interval, as plain lines, or in the -o format, and exit, without the table",
+		},
+		&cli.DurationFlag{
+			Name:    "emit-interval",
+			EnvVars: []string{"NSTATS_EMIT_INTERVAL"},
+			Usage:   "write one sample per action to the outputs every <duration>, its rates averaged over it, while sampling every --interval, e.g. 1s",
+		},
+		&cli.Float64Flag{
+			Name:    "min-pkt-size",
+			EnvVars: []string{"NSTATS_MIN_PKT_SIZE"},
+			Usage:   "highlight actions whose average packet size drops below <bytes> while they see traffic",
+		},
+		&cli.BoolFlag{
+			Name:    "verify-counters",
+			EnvVars: []string{"NSTATS_VERIFY_COUNTERS"},
+			Usage:   "report every counter that decreased between two reads of its map, which a healthy program never does",
+		},
+		&cli.BoolFlag{
+			Name:    "annotate-stdin",
+			EnvVars: []string{"NSTATS_ANNOTATE_STDIN"},
+			Usage:   "write every line read from stdin, e.g. \"phase:load-start\", as a timestamped annotation between the samples (json and csv only)",
+		},
+		&cli.IntFlag{
+			Name:    "output-buffer-size",
+			EnvVars: []string{"NSTATS_OUTPUT_BUFFER_SIZE"},
+			Usage:   "queue up to <n> samples in front of every sink so slow sinks don't delay sampling, 0 to write directly",
+		},
+		&cli.StringFlag{
+			Name:    "on-backpressure",
+			Value:   "block",
+			EnvVars: []string{"NSTATS_ON_BACKPRESSURE"},
+			Usage:   "what to do with samples when a sink's queue is full (block|drop)",
+		},
+		&cli.BoolFlag{
+			Name:    "journald",
+			EnvVars: []string{"NSTATS_JOURNALD"},
+			Usage:   "log to the systemd journal and write the samples matching --filter to it",
+		},
+		&cli.StringSliceFlag{
+			Name:    "sink",
+			EnvVars: []string{"NSTATS_SINK"},
+			Usage:   "also write samples to a sink, as name[:arg][,option...]: json[:path], csv[:path], prometheus:<addr|unix:path>, journald, statsd:<addr>, otlp:<url> or exec:<command>, with the options units=bits|bytes|human, precision=<n> and grouping (repeatable, or comma separated)",
+		},
+		&cli.StringFlag{
+			Name:    "ring-file",
+			EnvVars: []string{"NSTATS_RING_FILE"},
+			Usage:   "keep the last --ring-size samples in a fixed size binary file at <path>, read back with stats ring-dump",
+		},
+		&cli.IntFlag{
+			Name:    "ring-size",
+			Value:   3600,
+			EnvVars: []string{"NSTATS_RING_SIZE"},
+			Usage:   "number of samples kept in the --ring-file",
+		},
+		&cli.StringFlag{
+			Name:    "statsd",
+			EnvVars: []string{"NSTATS_STATSD"},
+			Usage:   "push metrics to the StatsD server at <host:port> every interval, as --sink statsd:<host:port>",
+		},
+		&cli.StringFlag{
+			Name:    "prometheus",
+			EnvVars: []string{"NSTATS_PROMETHEUS"},
+			Usage:   "serve the latest samples as Prometheus metrics at <host:port>/metrics, or on the Unix socket unix:<path>, as --sink prometheus:<addr>",
+		},
+		&cli.StringSliceFlag{
+			Name:    "exec",
+			EnvVars: []string{"NSTATS_EXEC"},
+			Usage:   "run <command> with /bin/sh every interval, the samples of the tick on its stdin as JSON lines; ticks are skipped while it still runs (repeatable)",
+		},
+		&cli.StringFlag{
+			Name:    "log-file",
+			EnvVars: []string{"NSTATS_LOG_FILE"},
+			Usage:   "append every sample to the CSV file <path> while the table runs, as --sink csv:<path>, a header being written only to new files",
+		},
+		&cli.StringFlag{
+			Name:    "log-max-size",
+			EnvVars: []string{"NSTATS_LOG_MAX_SIZE"},
+			Usage:   "rotate the --log-file once it grows past <size>, e.g. 100MiB, keeping the previous one as <path>.1; a .gz file counts its compressed size",
+		},
+		&cli.StringFlag{
+			Name:    "otlp",
+			EnvVars: []string{"NSTATS_OTLP"},
+			Usage:   "export metrics to the OTLP/HTTP collector at <url>, e.g. http://localhost:4318, every interval, as --sink otlp:<url>",
+		},
+		&cli.StringFlag{
+			Name:    "compare-baseline-file",
+			EnvVars: []string{"NSTATS_COMPARE_BASELINE_FILE"},
+			Usage:   "compare the packet rates with those of a snapshot saved with 's'",
+		},
+		&cli.StringFlag{
+			Name:    "scan",
+			EnvVars: []string{"NSTATS_SCAN"},
+			Usage:   "show every stats map pinned under <dir> instead of the one under --pin-path",
+		},
+		&cli.BoolFlag{
+			Name:    "keep-going",
+			Value:   true,
+			EnvVars: []string{"NSTATS_KEEP_GOING"},
+			Usage:   "when one of several maps fails to be read, show it as failed and go on with the others",
+		},
+		&cli.BoolFlag{
+			Name:    "fail-fast",
+			EnvVars: []string{"NSTATS_FAIL_FAST"},
+			Usage:   "stop as soon as any map fails to be read, the opposite of --keep-going",
+		},
+		&cli.StringSliceFlag{
+			Name:    "netns",
+			EnvVars: []string{"NSTATS_NETNS"},
+			Usage: "open the map in the network namespace <name>, or at a path such as /proc/<pid>/ns/net, " +
+				"showing each namespace's actions (repeatable). The pin path is not namespaced, a container's " +
+				"bpffs can be reached with --pin-path /proc/<pid>/root/sys/fs/bpf",
+		},
+		&cli.IntFlag{
+			Name:    "max-series",
+			Value:   stats.DefaultMaxSeries,
+			EnvVars: []string{"NSTATS_MAX_SERIES"},
+			Usage:   "export at most <n> series from prometheus sinks, 0 for no limit",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "don't warn about maps whose name doesn't look like a stats map",
+		},
+		&cli.BoolFlag{
+			Name:    "precision-auto",
+			EnvVars: []string{"NSTATS_PRECISION_AUTO"},
+			Usage:   "show the rates with decimals picked by their magnitude, e.g. 0.35 pps but 1,234,567 pps",
+		},
+		&cli.DurationFlag{
+			Name:    "warmup",
+			EnvVars: []string{"NSTATS_WARMUP"},
+			Usage:   "show \"warming up\" instead of the rates, and write no samples, for <duration> after the start (default one interval)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "derived",
+			EnvVars: []string{"NSTATS_DERIVED"},
+			Usage:   "show a metric computed from the actions' fields, e.g. \"drop_ratio=drop.pps/(pass.pps+drop.pps)\" (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "ratio",
+			EnvVars: []string{"NSTATS_RATIO"},
+			Usage:   "show the ratio of the rates of two actions, e.g. \"tx/pass\" or \"redirect/total\" (repeatable)",
+		},
+		&cli.BoolFlag{
+			Name:    "a11y",
+			EnvVars: []string{"NSTATS_A11Y"},
+			Usage:   "signal alerts with symbols and high-contrast styles instead of relying on color",
+		},
+		&cli.IntFlag{
+			Name:    "sig-figs",
+			EnvVars: []string{"NSTATS_SIG_FIGS"},
+			Usage:   "round the rates shown to <n> significant figures, e.g. 340k pps with 3",
+		},
+		&cli.BoolFlag{
+			Name:    "show-idle",
+			EnvVars: []string{"NSTATS_SHOW_IDLE"},
+			Usage:   "add a column showing how long each action's packet counter hasn't moved",
+		},
+		&cli.BoolFlag{
+			Name:    "hide-zero",
+			EnvVars: []string{"NSTATS_HIDE_ZERO"},
+			Usage:   "leave the actions that counted nothing yet out of the table, showing each as soon as it counts a packet",
+		},
+		&cli.StringFlag{
+			Name:    "value-schema",
+			EnvVars: []string{"NSTATS_VALUE_SCHEMA"},
+			Usage:   "decode map values with the schema <name>, or as the 64-bit counters <packets,bytes,counter,...>, showing the extra counters as columns, e.g. drop reasons",
+		},
+		&cli.BoolFlag{
+			Name:    "columns-from-map",
+			EnvVars: []string{"NSTATS_COLUMNS_FROM_MAP"},
+			Usage:   "head the columns after the fields of the --value-schema and show the rate of its extra counters",
+		},
+		&cli.StringSliceFlag{
+			Name:    "slo",
+			EnvVars: []string{"NSTATS_SLO"},
+			Usage:   "track the share of ticks an action meets a target, as action.field op number, e.g. drop.pps<50, reported in a column and at exit (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "alert",
+			EnvVars: []string{"NSTATS_ALERT"},
+			Usage:   "report on stderr, and in the JSON and CSV outputs, when a condition starts and stops holding, as action:field op number, e.g. XDP_ABORT:pps>100 (repeatable)",
+		},
+		&cli.Float64Flag{
+			Name:    "alert-drop-pps",
+			EnvVars: []string{"NSTATS_ALERT_DROP_PPS"},
+			Usage:   "alert when XDP_DROP goes over <n> pps, as --alert drop:pps><n>",
+		},
+		&cli.DurationFlag{
+			Name:    "notify-min-interval",
+			EnvVars: []string{"NSTATS_NOTIFY_MIN_INTERVAL"},
+			Usage:   "report each alert at most once per <duration>, an alert flapping in between being reported in the state it settles in, e.g. 1m",
+		},
+		&cli.DurationFlag{
+			Name:    "exit-after-idle",
+			EnvVars: []string{"NSTATS_EXIT_AFTER_IDLE"},
+			Usage:   "exit with the session summary once no packets were counted for <duration>, e.g. 30s, 0 to never",
+		},
+		&cli.BoolFlag{
+			Name:    "peak-hold",
+			EnvVars: []string{"NSTATS_PEAK_HOLD"},
+			Usage:   "add a column holding the peak rates of each action, slowly decaying, so brief spikes stay readable",
+		},
+		&cli.BoolFlag{
+			Name:    "show-average",
+			EnvVars: []string{"NSTATS_SHOW_AVERAGE"},
+			Usage:   "add a column with the average rates of each action since the start, to compare the current rates with",
+		},
+		&cli.BoolFlag{
+			Name:    "per-cpu",
+			EnvVars: []string{"NSTATS_PER_CPU"},
+			Usage:   "add a table of the rates of each action on each cpu, to spot the traffic landing on a few RX queues (with --cpus, of those cpus)",
+		},
+		&cli.BoolFlag{
+			Name:    "map-readonly",
+			Value:   true,
+			EnvVars: []string{"NSTATS_MAP_READONLY"},
+			Usage:   "open the stats maps read-only, --map-readonly=false opens them read-write for advanced uses",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "check the flags and that the map can be read, then exit",
+		},
+		&cli.BoolFlag{
+			Name:  "schema",
+			Usage: "print the JSON Schema of the json output and exit",
+		},
+		&cli.BoolFlag{
+			Name:  "help-actions",
+			Usage: "print what each XDP action means as tab separated key, name and description, and exit",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Bool("schema") {
+			schema, err := stats.Schema()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(schema))
+			return nil
+		}
+
+		if ctx.Bool("help-actions") {
+			for _, act := range stats.Actions() {
+				fmt.Printf("%d\t%s\t%s\n", act.Key, act.Name, act.Description)
+			}
+
+			return nil
+		}
+
+		if ctx.Float64("stable-reads-tolerance") < 0 {
+			return fmt.Errorf("invalid stable reads tolerance %g", ctx.Float64("stable-reads-tolerance"))
+		}
+
+		var picked []string
+		for _, name := range []string{"map-fd", "map-id", "map-name"} {
+			if ctx.IsSet(name) {
+				picked = append(picked, "--"+name)
+			}
+		}
+
+		if len(picked) > 1 {
+			return fmt.Errorf("%s each pick the map to read, give only one", strings.Join(picked, " and "))
+		}
+
+		if len(picked) == 1 && (ctx.IsSet("scan") || ctx.IsSet("netns") || ctx.Bool("mock")) {
+			return fmt.Errorf("%s reads a single map and can't be combined with --scan, --netns or --mock", picked[0])
+		}
+
+		if ctx.IsSet("log-max-size") && ctx.String("log-file") == "" {
+			return fmt.Errorf("--log-max-size rotates the --log-file, give one")
+		}
+
+		if ctx.Duration("notify-min-interval") < 0 {
+			return fmt.Errorf("invalid notify interval %s", ctx.Duration("notify-min-interval"))
+		}
+
+		if ctx.IsSet("notify-min-interval") && len(ctx.StringSlice("alert")) == 0 && !ctx.IsSet("alert-drop-pps") {
+			return fmt.Errorf("--notify-min-interval throttles the --alert reports, give one")
+		}
+
+		if ctx.Duration("exit-after-idle") < 0 {
+			return fmt.Errorf("invalid idle time %s", ctx.Duration("exit-after-idle"))
+		}
+
+		if ctx.Float64("mock-rate") <= 0 {
+			return fmt.Errorf("invalid mock rate %g", ctx.Float64("mock-rate"))
+		}
+
+		if ctx.Int("graph-history") < 1 {
+			return fmt.Errorf("invalid graph history %d, expected at least 1 tick", ctx.Int("graph-history"))
+		}
+
+		if ctx.Int("max-width") < 0 {
+			return fmt.Errorf("invalid max width %d", ctx.Int("max-width"))
+		}
+
+		if ctx.Int("column-width") < 0 {
+			return fmt.Errorf("invalid column width %d", ctx.Int("column-width"))
+		}
+
+		if ctx.Duration("rate-window") < 0 {
+			return fmt.Errorf("invalid rate window %s", ctx.Duration("rate-window"))
+		}
+
+		if ctx.Duration("snapshot-every") < 0 {
+			return fmt.Errorf("invalid snapshot interval %s", ctx.Duration("snapshot-every"))
+		}
+
+		if ctx.Int("snapshot-keep") < 0 {
+			return fmt.Errorf("invalid snapshot count %d", ctx.Int("snapshot-keep"))
+		}
+
+		if ctx.Duration("stale-after") < 0 {
+			return fmt.Errorf("invalid stale age %s", ctx.Duration("stale-after"))
+		}
+
+		if ctx.Bool("fail-fast") && ctx.IsSet("keep-going") && ctx.Bool("keep-going") {
+			return fmt.Errorf("--fail-fast and --keep-going are mutually exclusive")
+		}
+
+		if ctx.Int("sig-figs") < 0 {
+			return fmt.Errorf("invalid number of significant figures %d", ctx.Int("sig-figs"))
+		}
+
+		if ctx.Int("sig-figs") > 0 && ctx.Bool("precision-auto") {
+			return fmt.Errorf("--sig-figs and --precision-auto are mutually exclusive")
+		}
+
+		if ctx.Duration("warmup") < 0 {
+			return fmt.Errorf("invalid warmup %s", ctx.Duration("warmup"))
+		}
+
+		if ctx.Int("output-buffer-size") < 0 {
+			return fmt.Errorf("invalid output buffer size %d", ctx.Int("output-buffer-size"))
+		}
+
+		if p := ctx.String("on-backpressure"); p != "block" && p != "drop" {
+			return fmt.Errorf("invalid backpressure policy %q: expected block or drop", p)
+		}
+
+		if ctx.Int("render-fps") < 0 {
+			return fmt.Errorf("invalid render fps %d", ctx.Int("render-fps"))
+		}
+
+		if ctx.Int("max-series") < 0 {
+			return fmt.Errorf("invalid max series %d", ctx.Int("max-series"))
+		}
+
+		interval := ctx.Duration("interval")
+
+		if ctx.IsSet("hz") {
+			if ctx.IsSet("interval") {
+				return fmt.Errorf("--interval and --hz are mutually exclusive")
+			}
+
+			hz := ctx.Float64("hz")
+			if hz <= 0 {
+				return fmt.Errorf("invalid frequency %g", hz)
+			}
+
+			interval = time.Duration(float64(time.Second) / hz)
+			if interval < time.Millisecond {
+				return fmt.Errorf("invalid frequency %g: intervals under 1ms are not supported", hz)
+			}
+		}
+
+		// the other commands share the 1ms floor, shorter ticks only spin
+		if interval < time.Millisecond {
+			return fmt.Errorf("invalid interval %s: the minimum is 1ms", interval)
+		}
+
+		if jitter := ctx.Duration("interval-jitter"); jitter < 0 || jitter >= interval {
+			return fmt.Errorf("invalid interval jitter %s: must be at least 0 and under the interval of %s", jitter, interval)
+		}
+
+		if emit := ctx.Duration("emit-interval"); emit != 0 && emit < interval {
+			return fmt.Errorf("invalid emit interval %s: must be at least the interval of %s", emit, interval)
+		}
+
+		if ctx.Float64("min-pkt-size") < 0 {
+			return fmt.Errorf("invalid minimum packet size %g", ctx.Float64("min-pkt-size"))
+		}
+
+		output := ctx.String("output")
+		if ctx.String("template") != "" && !ctx.IsSet("output") {
+			output = "template"
+		}
+
+		if output != "table" && output != "json" && output != "csv" && output != "template" {
+			return fmt.Errorf("invalid output format %q", output)
+		}
+
+		if ctx.Int("count") < 0 {
+			return fmt.Errorf("invalid count %d", ctx.Int("count"))
+		}
+
+		count := ctx.Int("count")
+		if ctx.Bool("once") {
+			if ctx.IsSet("count") && count != 1 {
+				return fmt.Errorf("--once and --count %d disagree, pick one", count)
+			}
+
+			count = 1
+		}
+
+		// one-shot runs print plain lines rather than taking over the terminal
+		if count > 0 && output == "table" {
+			output = "text"
+		}
+
+		if output == "table" && !ctx.IsSet("output") && !isTerminal(os.Stdout) {
+			log.Println("stdout is not a terminal, writing JSON lines instead of the table (use -o table to force it)")
+			output = "json"
+		}
+
+		if ctx.Bool("annotate-stdin") && output == "table" {
+			return fmt.Errorf("--annotate-stdin needs a structured output, the table reads the keyboard")
+		}
+
+		var templateSink stats.OutputSink
+
+		if output == "template" {
+			if ctx.String("template") == "" {
+				return fmt.Errorf("output format template needs --template")
+			}
+
+			sink, err := stats.NewTemplateSink(os.Stdout, ctx.String("template"))
+			if err != nil {
+				return err
+			}
+
+			templateSink = sink
+		}
+
+		opts := stats.Options{
+			CompactNumbers:       ctx.Bool("compact-numbers"),
+			Label:                ctx.String("label"),
+			MaxWidth:             ctx.Int("max-width"),
+			FreezeColumns:        ctx.Bool("freeze-columns"),
+			ColumnWidth:          ctx.Int("column-width"),
+			ExtendedStats:        ctx.Bool("extended-stats"),
+			Graph:                ctx.Bool("graph"),
+			GraphHistory:         ctx.Int("graph-history"),
+			Efficiency:           ctx.Bool("efficiency"),
+			Interface:            ctx.String("iface"),
+			SnapshotDir:          ctx.String("snapshot-dir"),
+			RelativeTimestamps:   ctx.Bool("timestamps-relative"),
+			StableReads:          ctx.Bool("stable-reads"),
+			StableReadsTolerance: ctx.Float64("stable-reads-tolerance"),
+			Interval:             interval,
+			IntervalJitter:       ctx.Duration("interval-jitter"),
+			EmitInterval:         ctx.Duration("emit-interval"),
+			MinPacketSize:        ctx.Float64("min-pkt-size"),
+			RateWindow:           ctx.Duration("rate-window"),
+			RenderFPS:            ctx.Int("render-fps"),
+			Trend:                ctx.Bool("trend"),
+			ShowIdle:             ctx.Bool("show-idle"),
+			HideZero:             ctx.Bool("hide-zero"),
+			PeakHold:             ctx.Bool("peak-hold"),
+			ShowAverage:          ctx.Bool("show-average"),
+			PerCPU:               ctx.Bool("per-cpu"),
+			NotifyMinInterval:    ctx.Duration("notify-min-interval"),
+			ExitAfterIdle:        ctx.Duration("exit-after-idle"),
+			VerifyCounters:       ctx.Bool("verify-counters"),
+			NoRowSeparator:       ctx.Bool("no-separator"),
+			NoFillRow:            ctx.Bool("no-fill"),
+			SigFigs:              ctx.Int("sig-figs"),
+			Warmup:               ctx.Duration("warmup"),
+			A11y:                 ctx.Bool("a11y"),
+			KeepGoing:            ctx.Bool("keep-going") && !ctx.Bool("fail-fast"),
+			PrecisionAuto:        ctx.Bool("precision-auto"),
+			StaleAfter:           ctx.Duration("stale-after"),
+			ZeroOnStart:          ctx.Bool("zero-on-start"),
+			SinkBuffer:           ctx.Int("output-buffer-size"),
+			DropOnBackpressure:   ctx.String("on-backpressure") == "drop",
+		}
+
+		if spec := ctx.String("action-names"); spec != "" {
+			names, err := parseActionNames(spec)
+			if err != nil {
+				return err
+			}
+
+			opts.ActionNames = names
+		}
+
+		// a piece naming no group continues the actions of the one before
+		groupSpecs := joinSpecs(ctx.StringSlice("group-actions"), func(piece string) bool {
+			return strings.Contains(piece, "=")
+		})
+
+		for _, spec := range groupSpecs {
+			g, err := stats.ParseGroup(spec, opts.ActionNames)
+			if err != nil {
+				return err
+			}
+
+			opts.Groups = append(opts.Groups, g)
+		}
+
+		if ctx.Bool("total") {
+			opts.Groups = append(opts.Groups, stats.TotalGroup())
+		}
+
+		for _, spec := range ctx.StringSlice("slo") {
+			slo, err := stats.ParseSLO(spec, opts.ActionNames)
+			if err != nil {
+				return err
+			}
+
+			opts.SLOs = append(opts.SLOs, slo)
+		}
+
+		graphActions, err := stats.ParseGraphActions(ctx.String("graph-actions"), opts.ActionNames)
+		if err != nil {
+			return err
+		}
+
+		opts.GraphActions = graphActions
+
+		alerts := ctx.StringSlice("alert")
+		if ctx.IsSet("alert-drop-pps") {
+			alerts = append(alerts, fmt.Sprintf("drop:pps>%g", ctx.Float64("alert-drop-pps")))
+		}
+
+		for _, spec := range alerts {
+			alert, err := stats.ParseAlert(spec, opts.ActionNames)
+			if err != nil {
+				return err
+			}
+
+			opts.Alerts = append(opts.Alerts, alert)
+		}
+
+		for _, spec := range ctx.StringSlice("derived") {
+			d, err := stats.ParseDerived(spec, opts.ActionNames)
+			if err != nil {
+				return err
+			}
+
+			opts.Derived = append(opts.Derived, d)
+		}
+
+		for _, spec := range ctx.StringSlice("ratio") {
+			d, err := stats.ParseRatio(spec, opts.ActionNames)
+			if err != nil {
+				return err
+			}
+
+			opts.Derived = append(opts.Derived, d)
+		}
+
+		if file := ctx.String("compare-baseline-file"); file != "" {
+			snap, err := stats.LoadSnapshot(file)
+			if err != nil {
+				return err
+			}
+
+			opts.Baseline = &snap
+		}
+
+		colors, err := stats.ParseColorMode(ctx.String("color"))
+		if err != nil {
+			return err
+		}
+
+		if ctx.Bool("no-color") {
+			if ctx.IsSet("color") && colors != stats.ColorNever {
+				return fmt.Errorf("--no-color and --color %s disagree, pick one", colors)
+			}
+
+			colors = stats.ColorNever
+		}
+
+		opts.NoColor = !colors.Enabled(isTerminal(os.Stdout))
+
+		view, err := stats.ParseView(ctx.String("view"))
+		if err != nil {
+			return err
+		}
+
+		if ctx.Bool("cell-stacked") && view != stats.ViewBoth {
+			return fmt.Errorf("--cell-stacked stacks totals over rates and needs --view %s", stats.ViewBoth)
+		}
+
+		opts.View = view
+		opts.CellStacked = ctx.Bool("cell-stacked")
+
+		layout, err := stats.ParseLayout(ctx.String("layout"))
+		if err != nil {
+			return err
+		}
+
+		if layout == stats.LayoutGrouped && ctx.Bool("cell-stacked") {
+			return fmt.Errorf("--layout grouped and --cell-stacked both merge the totals with their rates, pick one")
+		}
+
+		opts.Layout = layout
+
+		clock, err := stats.ParseClock(ctx.String("clock"))
+		if err != nil {
+			return err
+		}
+
+		opts.Clock = clock
+
+		notation, err := stats.ParseNotation(ctx.String("notation"))
+		if err != nil {
+			return err
+		}
+
+		opts.Notation = notation
+
+		if field := ctx.String("rank-by"); field != "" {
+			ranking, err := stats.ParseRanking(field, ctx.Int("limit"))
+			if err != nil {
+				return err
+			}
+
+			opts.Ranking = ranking
+		} else if ctx.IsSet("limit") {
+			return fmt.Errorf("--limit needs --rank-by")
+		}
+
+		if spec := ctx.String("cpus"); spec != "" {
+			cpus, err := stats.ParseCPUSet(spec)
+			if err != nil {
+				return err
+			}
+
+			opts.CPUs = cpus
+		}
+
+		if spec := ctx.String("action-order"); spec != "" {
+			order, err := stats.ParseActionOrder(spec, opts.ActionNames)
+			if err != nil {
+				return err
+			}
+
+			opts.ActionOrder = order
+		}
+
+		if expr := ctx.String("filter"); expr != "" {
+			f, err := stats.ParseFilter(expr)
+			if err != nil {
+				return err
+			}
+
+			opts.Filter = f
+		}
+
+		if spec := ctx.String("value-schema"); spec != "" {
+			schema, err := stats.ParseValueSchema(spec)
+			if err != nil {
+				return err
+			}
+
+			stats.UseValueSchema(schema)
+		}
+
+		// the outputs are parsed and checked before anything is opened, so
+		// that a dry run catches their mistakes too
+		if addr := ctx.String("web"); addr != "" {
+			if err := stats.CheckListenAddr(addr); err != nil {
+				return fmt.Errorf("invalid --web address: %w", err)
+			}
+		}
+
+		if ctx.String("ring-file") != "" && ctx.Int("ring-size") <= 0 {
+			return fmt.Errorf("invalid ring size %d", ctx.Int("ring-size"))
+		}
+
+		// a piece naming no sink is an option of the one before
+		specs := joinSpecs(ctx.StringSlice("sink"), func(piece string) bool {
+			name, _, _ := strings.Cut(piece, ":")
+			for _, sink := range stats.Sinks() {
+				if name == sink {
+					return true
+				}
+			}
+
+			return false
+		})
+		if addr := ctx.String("statsd"); addr != "" {
+			specs = append(specs, "statsd:"+addr)
+		}
+
+		if addr := ctx.String("prometheus"); addr != "" {
+			specs = append(specs, "prometheus:"+addr)
+		}
+
+		if endpoint := ctx.String("otlp"); endpoint != "" {
+			specs = append(specs, "otlp:"+endpoint)
+		}
+
+		sinkSpecs := make([]stats.SinkSpec, 0, len(specs))
+		for _, spec := range specs {
+			s, err := stats.ParseSinkSpec(spec)
+			if err != nil {
+				return err
+			}
+
+			sinkSpecs = append(sinkSpecs, s)
+		}
+
+		var logMaxSize int64
+		if spec := ctx.String("log-max-size"); spec != "" {
+			if logMaxSize, err = stats.ParseSize(spec); err != nil {
+				return err
+			}
+		}
+
+		if err := stats.ValidateLogFile(ctx.String("log-file"), logMaxSize); err != nil {
+			return err
+		}
+
+		// commands may hold commas, so they don't go through the sink specs
+		execSpecs := make([]stats.SinkSpec, 0, len(ctx.StringSlice("exec")))
+		for _, command := range ctx.StringSlice("exec") {
+			s := stats.SinkSpec{Name: "exec", Arg: command}
+			if err := s.Validate(); err != nil {
+				return err
+			}
+
+			execSpecs = append(execSpecs, s)
+		}
+
+		var maps []*statsMap
+		switch namespaces := ctx.StringSlice("netns"); {
+		case ctx.Bool("mock"):
+			// no maps, the sources are made up below
+		case ctx.IsSet("map-fd"):
+			var m *statsMap
+			if m, err = openStatsMapFD(ctx.Int("map-fd"), opts.ActionNames); err == nil {
+				maps = []*statsMap{m}
+			}
+		case ctx.IsSet("map-id"):
+			var m *statsMap
+			if m, err = openStatsMapID(ctx.Uint("map-id"), opts.ActionNames); err == nil {
+				maps = []*statsMap{m}
+			}
+		case ctx.IsSet("map-name"):
+			var m *statsMap
+			if m, err = openStatsMapName(ctx.String("map-name"), opts.ActionNames); err == nil {
+				maps = []*statsMap{m}
+			}
+		case len(namespaces) > 0:
+			maps, err = openNetnsMaps(namespaces, ctx.String("scan"), opts.ActionNames, ctx.Bool("map-readonly"))
+		default:
+			maps, err = openStatsMaps(ctx.String("scan"), opts.ActionNames, ctx.Bool("map-readonly"))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			for _, m := range maps {
+				m.Close()
+			}
+		}()
+
+		if !ctx.Bool("force") {
+			for _, m := range maps {
+				warnMapName(m.path, m.info)
+			}
+		}
+
+		// the extra counters get columns from the first map having any
+		for _, m := range maps {
+			if schema, ok := stats.ValueSchemaFor(int(m.info.ValueSize)); ok && len(schema.Counters) > 0 {
+				opts.Counters = schema.Counters
+
+				if ctx.Bool("columns-from-map") {
+					opts.ColumnsFromMap = &schema
+				}
+
+				break
+			}
+		}
+
+		sources := make([]stats.Source, 0, len(maps))
+		for _, m := range maps {
+			s, err := m.sources()
+			if err != nil {
+				return fmt.Errorf("error reading map at %s: %w", m.path, err)
+			}
+
+			sources = append(sources, s...)
+		}
+
+		if ctx.Bool("mock") {
+			sources = append(sources, stats.Source{Map: stats.NewMockMap(ctx.Float64("mock-rate"))})
+		}
+
+		if ctx.Bool("dry-run") {
+			for _, m := range maps {
+				srcs, _ := m.sources()
+
+				for _, src := range srcs {
+					if err := stats.Check(src.Map); err != nil {
+						return fmt.Errorf("error reading map at %s: %w", m.path, err)
+					}
+				}
+
+				fmt.Printf("dry run ok: %s is readable\n", m.describe())
+			}
+
+			return nil
+		}
+
+		// the graph draws from the history too
+		historySize := stats.DefaultHistorySize
+		if opts.GraphHistory > historySize {
+			historySize = opts.GraphHistory
+		}
+
+		if addr := ctx.String("web"); addr != "" {
+			opts.History = stats.NewHistory(historySize)
+
+			stop, err := serveWeb(addr, opts.History)
+			if err != nil {
+				return err
+			}
+
+			defer stop()
+		}
+
+		if every := ctx.Duration("snapshot-every"); every > 0 {
+			if opts.History == nil {
+				opts.History = stats.NewHistory(historySize)
+			}
+
+			rotateCtx, stop := context.WithCancel(ctx.Context)
+			defer stop()
+
+			go stats.RotateSnapshots(rotateCtx, opts.History, opts.SnapshotDir, every, ctx.Int("snapshot-keep"))
+		}
+
+		if n := ctx.Int("max-series"); n != stats.DefaultMaxSeries {
+			stats.RegisterSink("prometheus", func(addr string) (stats.OutputSink, error) {
+				return stats.NewPrometheusSink(addr, n)
+			})
+		}
+
+		if ctx.Bool("journald") {
+			if sink, err := stats.NewJournaldSink(); err != nil {
+				log.Printf("not logging to the journal: %v\n", err)
+			} else {
+				defer sink.Close()
+
+				opts.Sinks = append(opts.Sinks, sink)
+			}
+
+			if conn, err := journald.Dial(); err == nil {
+				log.SetFlags(0)
+				log.SetOutput(conn.Writer(journald.PriInfo))
+
+				// the error returned, if any, is logged after this
+				defer func() {
+					log.SetOutput(os.Stderr)
+					log.SetFlags(log.LstdFlags)
+					conn.Close()
+				}()
+			}
+		}
+
+		if path := ctx.String("ring-file"); path != "" {
+			ring, err := stats.NewRingSink(path, ctx.Int("ring-size"))
+			if err != nil {
+				return err
+			}
+
+			defer ring.Close()
+
+			opts.Sinks = append(opts.Sinks, ring)
+		}
+
+		for _, spec := range sinkSpecs {
+			sink, err := spec.Open()
+			if err != nil {
+				return err
+			}
+
+			defer sink.Close()
+
+			opts.Sinks = append(opts.Sinks, sink)
+		}
+
+		// paths may hold commas too
+		if path := ctx.String("log-file"); path != "" {
+			sink, err := stats.NewCSVLogSink(path, logMaxSize)
+			if err != nil {
+				return err
+			}
+
+			defer sink.Close()
+
+			opts.Sinks = append(opts.Sinks, sink)
+		}
+
+		for _, spec := range execSpecs {
+			sink, err := spec.Open()
+			if err != nil {
+				return err
+			}
+
+			defer sink.Close()
+
+			opts.Sinks = append(opts.Sinks, sink)
+		}
+
+		verbose := ctx.Bool("verbose")
+
+		if output != "table" {
+			if verbose {
+				for _, m := range maps {
+					log.Println(m.describe())
+				}
+			}
+
+			switch {
+			case templateSink != nil:
+				opts.Sinks = append(opts.Sinks, templateSink)
+			case output == "csv":
+				opts.Sinks = append(opts.Sinks, stats.NewCSVSink(os.Stdout))
+			case output == "text":
+				text := stats.NewTextSink(os.Stdout)
+				defer text.Close()
+
+				opts.Sinks = append(opts.Sinks, text)
+			default:
+				opts.Sinks = append(opts.Sinks, stats.NewJSONSink(os.Stdout))
+			}
+
+			opts.Count = count
+
+			if ctx.Bool("annotate-stdin") {
+				opts.Annotations = stats.ReadAnnotations(os.Stdin)
+			}
+
+			sigCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt)
+			defer stop()
+
+			return stats.StreamMaps(sigCtx, sources, opts)
+		}
+
+		fmt.Println("Collecting stats from BPF map")
+
+		if ctx.Bool("compact-header") {
+			names := make([]string, 0, len(maps))
+			for _, m := range maps {
+				names = append(names, m.short())
+			}
+
+			if ctx.Bool("mock") {
+				names = append(names, "mock traffic")
+			}
+
+			opts.CompactHeader = strings.Join(names, ", ")
+		}
+
+		// shown in the table's map panel, printing would scroll away under it
+		if verbose {
+			for _, m := range maps {
+				opts.Metadata = append(opts.Metadata, m.describe())
+			}
+		}
+
+		// the terminal sends ^C as a key press, SIGTERM still needs handling to
+		// restore the terminal
+		sigCtx, stop := signal.NotifyContext(ctx.Context, syscall.SIGTERM)
+		defer stop()
+
+		return stats.RenderMaps(sigCtx, sources, opts)
+	},
+}
+
+var resetCommand = cli.Command{
+	Name:  "reset",
+	Usage: "zero the kernel counters of every action",
 	Flags: []cli.Flag{
+		&pinPathFlag,
 		&cli.BoolFlag{
-			Name:    "verbose",
-			Usage:   "print extra information",
-			Aliases: []string{"v"},
+			Name:  "force",
+			Usage: "confirm resetting the counters, which affects everything reading the map",
 		},
 	},
 	Action: func(ctx *cli.Context) error {
 		mapPath := path.Join(pinPath, "xdp_stats_map")
 
-		log.Printf("Loading pinned map at %s\n\n", mapPath)
-		statsMap, err := ebpf.LoadPinnedMap(mapPath, &ebpf.LoadPinOptions{
-			ReadOnly: true,
-		})
+		if !ctx.Bool("force") {
+			return fmt.Errorf("refusing to reset the counters of %s without --force", mapPath)
+		}
 
+		statsMap, info, err := loadStatsMap(mapPath, false)
 		if err != nil {
-			return fmt.Errorf("error loading pinned map at %s: %w", mapPath, err)
+			return err
 		}
 
 		defer statsMap.Close()
 
-		info, err := statsMap.Info()
+		// --force confirms the reset here, so the name is always checked
+		warnMapName(mapPath, info)
+
+		if err := stats.Reset(statsMap); err != nil {
+			return err
+		}
+
+		log.Printf("Reset the counters of %s\n", mapPath)
+		return nil
+	},
+}
+
+var benchCommand = cli.Command{
+	Name:  "bench",
+	Usage: "sample for a fixed time and summarise the throughput of every action",
+	Flags: []cli.Flag{
+		&pinPathFlag,
+		&cli.DurationFlag{
+			Name:  "duration",
+			Value: 30 * time.Second,
+			Usage: "how long to sample for",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the summary as JSON",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Duration("duration") <= 0 {
+			return fmt.Errorf("invalid duration %s", ctx.Duration("duration"))
+		}
+
+		maps, err := openStatsMaps("", nil, true)
 		if err != nil {
-			return fmt.Errorf("error getting map info: %w", err)
+			return err
 		}
 
-		id, ok := info.ID()
-		if !ok {
-			log.Println("map ID field not available")
+		m := maps[0]
+		defer m.Close()
+
+		// ^C ends the run early, still printing the summary
+		sigCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt)
+		defer stop()
+
+		log.Printf("Sampling for %s\n", ctx.Duration("duration"))
+
+		report, err := stats.Bench(sigCtx, []stats.Source{{Map: m}}, stats.Options{}, ctx.Duration("duration"))
+		if err != nil {
+			return err
 		}
 
-		verbose := ctx.Bool("verbose")
+		if ctx.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
 
-		fmt.Println("Collecting stats from BPF map")
+			return enc.Encode(report)
+		}
 
-		if verbose {
-			fmt.Printf(" - BPF map (bpf_map_type: %d) id: %d name: %s ", info.Type, id, info.Name)
-			fmt.Printf("key_size: %d value_size: %d max entries: %d\n\n", info.KeySize, info.ValueSize, info.MaxEntries)
+		return report.WriteText(os.Stdout)
+	},
+}
+
+var sampleCommand = cli.Command{
+	Name:  "sample",
+	Usage: "read a few intervals and print all their samples with a summary in one document",
+	Flags: []cli.Flag{
+		&pinPathFlag,
+		&cli.IntFlag{
+			Name:  "samples",
+			Value: 5,
+			Usage: "number of intervals to read",
+		},
+		&cli.DurationFlag{
+			Name:  "interval",
+			Value: stats.DefaultInterval,
+			Usage: "time between two samples",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "json",
+			Usage: "json for every sample and the summary, or text for the summary only",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Int("samples") < 1 {
+			return fmt.Errorf("invalid number of samples %d", ctx.Int("samples"))
+		}
+
+		if ctx.Duration("interval") < time.Millisecond {
+			return fmt.Errorf("invalid interval %s: the minimum is 1ms", ctx.Duration("interval"))
+		}
+
+		format := ctx.String("format")
+		if format != "json" && format != "text" {
+			return fmt.Errorf("invalid format %q: expected json or text", format)
+		}
+
+		maps, err := openStatsMaps("", nil, true)
+		if err != nil {
+			return err
+		}
+
+		m := maps[0]
+		defer m.Close()
+
+		// ^C ends the run early, still printing what was read
+		sigCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt)
+		defer stop()
+
+		opts := stats.Options{Interval: ctx.Duration("interval")}
+
+		profile, err := stats.SampleProfile(sigCtx, []stats.Source{{Map: m}}, opts, ctx.Int("samples"))
+		if err != nil {
+			return err
+		}
+
+		if format == "text" {
+			return profile.Summary.WriteText(os.Stdout)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(profile)
+	},
+}
+
+var ringDumpCommand = cli.Command{
+	Name:      "ring-dump",
+	Usage:     "print the samples kept in a --ring-file as JSON lines, oldest first",
+	ArgsUsage: "<path>",
+	Action: func(ctx *cli.Context) error {
+		if ctx.NArg() != 1 {
+			return fmt.Errorf("expected the path of a ring file")
 		}
 
-		if err := stats.RenderStats(statsMap); err != nil {
-			log.Fatal(err)
+		samples, err := stats.ReadRing(ctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		for _, s := range samples {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	},
 }
+
+// loadStatsMap opens the pinned stats map and checks that its layout is what
+// the collector expects.
+func loadStatsMap(mapPath string, readOnly bool) (*ebpf.Map, *ebpf.MapInfo, error) {
+	log.Printf("Loading pinned map at %s\n\n", mapPath)
+
+	statsMap, info, err := stats.LoadMap(mapPath, readOnly)
+
+	if err != nil {
+		return nil, nil, mapLoadError(err)
+	}
+
+	if info.Type == ebpf.PerCPUArray && info.MaxEntries > stats.MaxActions {
+		log.Printf("WARNING: map at %s has %d entries but only the first %d are shown, "+
+			"counters for the other %d keys are not displayed\n",
+			mapPath, info.MaxEntries, stats.MaxActions, info.MaxEntries-stats.MaxActions)
+	}
+
+	return statsMap, info, nil
+}
+
+// mapLoadError adds a hint to the usual errors of loading a stats map
+func mapLoadError(err error) error {
+	switch {
+	case errors.Is(err, stats.ErrMapNotFound):
+		return fmt.Errorf("%w (is the program loaded? see the load command and --pin-path)", err)
+	case errors.Is(err, stats.ErrPermission):
+		return fmt.Errorf("%w (reading BPF maps needs root or CAP_BPF)", err)
+	default:
+		return err
+	}
+}
+
+// statsMap is a stats map opened by the stats command
+type statsMap struct {
+	*ebpf.Map
+
+	// looker looks the actions up by the key size of the map
+	looker stats.MapLooker
+
+	// name tags the samples of the map when several are scanned
+	name string
+	path string
+	info *ebpf.MapInfo
+	id   ebpf.MapID
+
+	// hasID is false on kernels not reporting map IDs
+	hasID bool
+}
+
+// Lookup reads the value of key, an action given as a *uint32 being converted
+// to the key size of the map
+func (m *statsMap) Lookup(key, valueOut interface{}) error {
+	return m.looker.Lookup(key, valueOut)
+}
+
+// newStatsMap wraps m, reading its actions by the key size of info
+func newStatsMap(m *ebpf.Map, info *ebpf.MapInfo, name, path string) (*statsMap, error) {
+	looker, err := stats.KeyedMap(m, info.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("map at %s: %w", path, err)
+	}
+
+	id, hasID := info.ID()
+
+	return &statsMap{Map: m, looker: looker, name: name, path: path, info: info, id: id, hasID: hasID}, nil
+}
+
+// short names m and its ID, e.g. "xdp_stats_map id 42"
+func (m *statsMap) short() string {
+	if !m.hasID {
+		return m.info.Name + " id n/a"
+	}
+
+	return fmt.Sprintf("%s id %d", m.info.Name, m.id)
+}
+
+// describe returns the ID, layout and pin of m in one line
+func (m *statsMap) describe() string {
+	id := "n/a (not reported by the kernel)"
+	if m.hasID {
+		id = fmt.Sprint(m.id)
+	}
+
+	return fmt.Sprintf("map %s (id: %s type: %s key_size: %d value_size: %d max entries: %d) at %s",
+		m.info.Name, id, m.info.Type, m.info.KeySize, m.info.ValueSize, m.info.MaxEntries, m.path)
+}
+
+// sources returns the sources reading m: the map itself, or one per sub key
+// for maps with composite keys
+func (m *statsMap) sources() ([]stats.Source, error) {
+	if m.info.KeySize == stats.CompositeKeySize {
+		return stats.CompositeSources(m, stats.ActionProtoKey{}, m.name)
+	}
+
+	return []stats.Source{{Name: m.name, Map: m}}, nil
+}
+
+// openStatsMaps opens the stats map under the pin path, or with a scan
+// directory every stats map pinned below it. Pins that are not stats maps, or
+// whose entries don't fit the action names, are reported and skipped. Maps
+// are only opened read-write when readOnly is false, with a warning.
+func openStatsMaps(scanDir string, names stats.ActionNames, readOnly bool) ([]*statsMap, error) {
+	if !readOnly {
+		log.Println("WARNING: opening the stats maps read-write, a bug here could corrupt the counters the loaded program relies on")
+	}
+
+	if scanDir == "" {
+		m, err := openStatsMap(path.Join(pinPath, "xdp_stats_map"), "", names, readOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*statsMap{m}, nil
+	}
+
+	var maps []*statsMap
+
+	err := filepath.WalkDir(scanDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		name, err := filepath.Rel(scanDir, p)
+		if err != nil {
+			return err
+		}
+
+		m, err := openStatsMap(p, name, names, readOnly)
+		if err != nil {
+			log.Printf("skipping %s: %v\n", p, err)
+			return nil
+		}
+
+		maps = append(maps, m)
+
+		return nil
+	})
+
+	if err == nil && len(maps) == 0 {
+		err = fmt.Errorf("no stats maps found under %s", scanDir)
+	}
+
+	if err != nil {
+		for _, m := range maps {
+			m.Close()
+		}
+
+		return nil, err
+	}
+
+	return maps, nil
+}
+
+func openStatsMap(mapPath, name string, names stats.ActionNames, readOnly bool) (*statsMap, error) {
+	m, info, err := loadStatsMap(mapPath, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := names.Validate(info.MaxEntries); err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	sm, err := newStatsMap(m, info, name, mapPath)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	return sm, nil
+}
+
+// openStatsMapFD opens the stats map behind an inherited file descriptor
+func openStatsMapFD(fd int, names stats.ActionNames) (*statsMap, error) {
+	m, info, err := stats.LoadMapFD(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	return adoptStatsMap(m, info, names, fmt.Sprintf("fd %d", fd))
+}
+
+// openStatsMapID opens the stats map of a BPF map ID
+func openStatsMapID(id uint, names stats.ActionNames) (*statsMap, error) {
+	m, info, err := stats.LoadMapID(ebpf.MapID(id))
+	if err != nil {
+		return nil, mapLoadError(err)
+	}
+
+	return adoptStatsMap(m, info, names, fmt.Sprintf("id %d", id))
+}
+
+// openStatsMapName opens the loaded stats map of the given name
+func openStatsMapName(name string, names stats.ActionNames) (*statsMap, error) {
+	m, info, err := stats.LoadMapName(name)
+	if err != nil {
+		return nil, mapLoadError(err)
+	}
+
+	id, _ := info.ID()
+
+	return adoptStatsMap(m, info, names, fmt.Sprintf("id %d", id))
+}
+
+// adoptStatsMap checks that the map m, found at where, fits the action names
+// and wraps it for the stats command, closing it if it doesn't
+func adoptStatsMap(m *ebpf.Map, info *ebpf.MapInfo, names stats.ActionNames, where string) (*statsMap, error) {
+	if err := names.Validate(info.MaxEntries); err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	sm, err := newStatsMap(m, info, "", where)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	return sm, nil
+}
+
+// warnMapName warns if the name of the map at mapPath doesn't look like that
+// of a stats map
+func warnMapName(mapPath string, info *ebpf.MapInfo) {
+	if !stats.LooksLikeStatsMap(info.Name) {
+		log.Printf("WARNING: map %q at %s does not look like an xdp stats map, check the pin path\n",
+			info.Name, mapPath)
+	}
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// joinSpecs joins back the specs of a slice flag that were split at their
+// commas, as both the flag and its environment variable are. A piece starts
+// a spec of its own only if starts says so, e.g. handled=pass,tx,drop=drop
+// holding the specs handled=pass,tx and drop=drop.
+func joinSpecs(pieces []string, starts func(piece string) bool) []string {
+	var specs []string
+
+	for _, piece := range pieces {
+		if len(specs) > 0 && !starts(piece) {
+			specs[len(specs)-1] += "," + piece
+			continue
+		}
+
+		specs = append(specs, piece)
+	}
+
+	return specs
+}
+
+// parseActionNames reads the action names from the file named by spec, or
+// parses spec itself if there is no such file.
+func parseActionNames(spec string) (stats.ActionNames, error) {
+	if _, err := os.Stat(spec); err == nil {
+		return stats.LoadActionNames(spec)
+	}
+
+	return stats.ParseActionNames(spec)
+}
+
+// serveWeb serves the dashboard for history on addr, host:port or
+// unix:/path, in the background. The returned function shuts the server down.
+func serveWeb(addr string, history *stats.History) (func(), error) {
+	ln, err := stats.Listen(addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{Handler: web.Handler(history)}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("web server: %v\n", err)
+		}
+	}()
+
+	if ln.Addr().Network() == "unix" {
+		log.Printf("Serving the dashboard on unix socket %s\n", ln.Addr())
+	} else {
+		log.Printf("Serving the dashboard on http://%s\n", ln.Addr())
+	}
+
+	return func() { srv.Shutdown(context.Background()) }, nil
+}
@@ -0,0 +1,55 @@
+package stats_test
+
+import (
+	"fmt"
+
+	"github.com/bxffour/nstats/internal/stats/statstest"
+	"github.com/bxffour/nstats/stats"
+)
+
+func ExampleReader() {
+	m := statstest.NewMap(5, 2)
+	m.Add(2, 0, 10, 1500)
+
+	r := stats.NewReader(m)
+
+	// the first read has the totals alone
+	if _, err := r.Sample(); err != nil {
+		panic(err)
+	}
+
+	m.Add(2, 1, 5, 500)
+
+	actions, err := r.Sample()
+	if err != nil {
+		panic(err)
+	}
+
+	pass := actions[2]
+	fmt.Println(pass.Action, pass.Packets, pass.Bytes)
+	// Output: XDP_PASS 15 2000
+}
+
+// countSink counts the samples of every action
+type countSink struct{ counts map[string]int }
+
+func (c *countSink) Write(s stats.Sample) error { c.counts[s.Action]++; return nil }
+func (c *countSink) Close() error               { return nil }
+
+func ExampleRegisterSink() {
+	sink := &countSink{counts: map[string]int{}}
+
+	stats.RegisterSink("count", func(string) (stats.OutputSink, error) {
+		return sink, nil
+	})
+
+	s, err := stats.NewSink("count")
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	s.Write(stats.Sample{Action: "XDP_PASS"})
+	fmt.Println(sink.counts["XDP_PASS"])
+	// Output: 1
+}
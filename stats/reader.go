@@ -0,0 +1,25 @@
+// Package stats reads the counters of the XDP stats maps nstats displays, for
+// programs embedding the collector without its terminal UI, and registers the
+// sinks the samples of a session can be written to. The collector
+// itself lives in an internal package; this package is its public face, and
+// its types are the collector's own.
+package stats
+
+import "github.com/bxffour/nstats/internal/stats"
+
+// MapLooker is the subset of *ebpf.Map the collector depends on.
+type MapLooker = stats.MapLooker
+
+// ActionStat is the statistics of one action over the period since the
+// previous read, as returned by Reader.Sample.
+type ActionStat = stats.ActionStat
+
+// Reader reads the counters of a stats map on demand, each read computing the
+// rates since the previous one.
+type Reader = stats.Reader
+
+// NewReader returns a Reader of statsMap, an *ebpf.Map or anything looking up
+// its values the same way. Nothing is read until Sample is called.
+func NewReader(statsMap MapLooker) *Reader {
+	return stats.NewReader(statsMap)
+}
@@ -0,0 +1,33 @@
+package stats
+
+import "github.com/bxffour/nstats/internal/stats"
+
+// Sample is the statistics of a single XDP action over one sampling period,
+// as written to every sink.
+type Sample = stats.Sample
+
+// OutputSink receives the samples of a session: Write is called for every
+// action and group on each tick, from the goroutine collecting the stats.
+// Close is up to whoever created the sink.
+type OutputSink = stats.OutputSink
+
+// SinkFactory creates a sink from the argument following the sink name in a
+// sink spec, which is empty if the spec has none.
+type SinkFactory = stats.SinkFactory
+
+// RegisterSink makes a sink available under name to NewSink and to the
+// --sink flag, replacing any sink registered with the same name.
+func RegisterSink(name string, factory SinkFactory) {
+	stats.RegisterSink(name, factory)
+}
+
+// NewSink creates a registered sink from a spec of the form
+// name[:arg][,option...], e.g. "csv:out.csv,precision=2".
+func NewSink(spec string) (OutputSink, error) {
+	return stats.NewSink(spec)
+}
+
+// Sinks returns the names of the registered sinks.
+func Sinks() []string {
+	return stats.Sinks()
+}